@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// convert is called for 'go run . convert [options] <in> <out>'.
+// It reads a world in any supported format and writes it in the format given by the extension of <out>.
+func convert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go run . convert [options] <in> <out>")
+		fmt.Fprintln(flags.Output(), "Reads pgm, png, jpeg, gif, .ckpt, .mc and .rle. Writes pgm, png, .ckpt, .mc and .rle.")
+		flags.PrintDefaults()
+	}
+
+	crop := flags.String(
+		"crop",
+		"",
+		"Crop to the region x,y,w,h before resizing.")
+
+	size := flags.String(
+		"size",
+		"",
+		"Pad or crop to WxH. Defaults to the size of the input.")
+
+	centre := flags.Bool(
+		"centre",
+		false,
+		"Keep the world centred when resizing instead of anchoring it at the top left.")
+
+	generations := flags.Int(
+		"generations",
+		0,
+		"Evolve the world this many generations before writing it. Defaults to 0.")
+
+	threads := flags.Int(
+		"t",
+		8,
+		"Specify the number of worker threads to use when evolving. Defaults to 8.")
+
+	threshold := flags.Uint(
+		"threshold",
+		128,
+		"Specify the brightness (1-255) at which an image pixel becomes alive. Defaults to 128.")
+
+	dither := flags.Bool(
+		"dither",
+		false,
+		"Convert images with Floyd-Steinberg dithering instead of a plain threshold.")
+
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	in, out := flags.Arg(0), flags.Arg(1)
+	if *threshold < 1 || *threshold > 255 {
+		fmt.Fprintf(os.Stderr, "-threshold must be between 1 and 255, not %v\n", *threshold)
+		os.Exit(1)
+	}
+	if *generations < 0 {
+		fmt.Fprintf(os.Stderr, "-generations must not be negative, not %v\n", *generations)
+		os.Exit(1)
+	}
+
+	world, turn, err := gol.ReadWorld(in, uint8(*threshold), *dither)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *crop != "" {
+		worldWidth := 0
+		if len(world) > 0 {
+			worldWidth = len(world[0])
+		}
+		x, y, width, height, err := parseCrop(*crop, worldWidth, len(world))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "-crop:", err)
+			os.Exit(1)
+		}
+		world = gol.CropWorld(world, x, y, width, height)
+	}
+
+	if *size != "" {
+		width, height, err := parseSize(*size)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "-size:", err)
+			os.Exit(1)
+		}
+		world = gol.ResizeWorld(world, width, height, *centre)
+	}
+
+	if *generations > 0 {
+		world = gol.EvolveWorld(world, *generations, *threads)
+	}
+
+	if err := gol.WriteWorld(out, world, turn+*generations); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Converted", in, "to", out)
+}
+
+// parseCrop parses the x,y,w,h region of a worldWidth x worldHeight world given to -crop.
+// The region may run off the edge of the world, but its top left corner must be inside it.
+func parseCrop(value string, worldWidth, worldHeight int) (x, y, width, height int, err error) {
+	if _, err := fmt.Sscanf(value, "%d,%d,%d,%d", &x, &y, &width, &height); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("expected x,y,w,h, got %q", value)
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, 0, 0, fmt.Errorf("region must be at least 1x1, not %vx%v", width, height)
+	}
+	if x < 0 || y < 0 || x >= worldWidth || y >= worldHeight {
+		return 0, 0, 0, 0, fmt.Errorf("origin (%v, %v) is outside the %vx%v world", x, y, worldWidth, worldHeight)
+	}
+	return x, y, width, height, nil
+}
+
+// parseSize parses the WxH given to -size.
+func parseSize(value string) (width, height int, err error) {
+	if _, err := fmt.Sscanf(value, "%dx%d", &width, &height); err != nil {
+		return 0, 0, fmt.Errorf("expected WxH, got %q", value)
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("size must be at least 1x1, not %vx%v", width, height)
+	}
+	return width, height, nil
+}
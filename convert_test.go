@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+// TestParseCrop checks that -crop rejects empty regions and origins outside the world,
+// but allows a region that runs off the edge.
+func TestParseCrop(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"0,0,16,16", true},
+		{"8,8,16,16", true},
+		{"15,15,1,1", true},
+		{"0,0,0,16", false},
+		{"0,0,16,-1", false},
+		{"-1,0,4,4", false},
+		{"0,-1,4,4", false},
+		{"16,0,4,4", false},
+		{"0,16,4,4", false},
+		{"0,0,16", false},
+	}
+	for _, test := range tests {
+		_, _, _, _, err := parseCrop(test.value, 16, 16)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("-crop %v: expected valid %v, got error %v", test.value, test.valid, err)
+		}
+	}
+}
+
+// TestParseSize checks that -size rejects a width or height that is not positive.
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"16x16", true},
+		{"1x1", true},
+		{"0x16", false},
+		{"16x0", false},
+		{"-4x16", false},
+		{"16", false},
+	}
+	for _, test := range tests {
+		_, _, err := parseSize(test.value)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("-size %v: expected valid %v, got error %v", test.value, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package gol
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkpointMagic starts every checkpoint so that it can be recognised without relying on the extension.
+const checkpointMagic = "GOLCHECKPOINT 1\n"
+
+// checkpointExtension is appended to the output template to name checkpoint files.
+const checkpointExtension = ".ckpt"
+
+// checkpointHeader describes the world stored in a checkpoint.
+// It is written as a single line of JSON after checkpointMagic, followed by the bit-packed world.
+type checkpointHeader struct {
+	Turn     int
+	Width    int
+	Height   int
+	Turns    int
+	Threads  int
+	Rule     string
+	Checksum uint32
+}
+
+// isCheckpointFile reports whether filename should be read as a checkpoint.
+func isCheckpointFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), checkpointExtension)
+}
+
+// checkpointPath returns the path of the checkpoint file called filename.
+func checkpointPath(p Params, filename string) string {
+	return strings.TrimSuffix(outputPath(p, filename), ".pgm") + checkpointExtension
+}
+
+// packWorld stores one cell per bit, row by row, with the first cell in the most significant bit.
+func packWorld(world [][]byte) []byte {
+	var packed []byte
+	var current byte
+	bits := 0
+	for _, row := range world {
+		for _, cell := range row {
+			current <<= 1
+			if cell == aliveCell {
+				current |= 1
+			}
+			bits++
+			if bits == 8 {
+				packed = append(packed, current)
+				current, bits = 0, 0
+			}
+		}
+	}
+	if bits > 0 {
+		packed = append(packed, current<<uint(8-bits))
+	}
+	return packed
+}
+
+// unpackWorld is the inverse of packWorld. packed must hold at least width*height bits.
+func unpackWorld(packed []byte, width, height int) [][]byte {
+	world := makeWorld(height, width)
+	i := 0
+	for y := range world {
+		for x := range world[y] {
+			if packed[i/8]&(0x80>>uint(i%8)) != 0 {
+				world[y][x] = aliveCell
+			}
+			i++
+		}
+	}
+	return world
+}
+
+// writeCheckpoint writes a gzip compressed checkpoint of the world after header.Turn turns.
+// The checksum in header is filled in from the world.
+func writeCheckpoint(w io.Writer, header checkpointHeader, world [][]byte) error {
+	packed := packWorld(world)
+	header.Checksum = crc32.ChecksumIEEE(packed)
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	compressed := gzip.NewWriter(w)
+	if _, err := io.WriteString(compressed, checkpointMagic); err != nil {
+		return err
+	}
+	if _, err := compressed.Write(append(encodedHeader, '\n')); err != nil {
+		return err
+	}
+	if _, err := compressed.Write(packed); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// readCheckpointHeader reads the header of a checkpoint, leaving r at the start of the packed world.
+func readCheckpointHeader(r *bufio.Reader) (checkpointHeader, error) {
+	var header checkpointHeader
+
+	magic, err := r.ReadString('\n')
+	if err != nil || magic != checkpointMagic {
+		return header, fmt.Errorf("not a checkpoint file")
+	}
+
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return header, fmt.Errorf("truncated checkpoint header: %v", err)
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return header, fmt.Errorf("invalid checkpoint header: %v", err)
+	}
+	if err := checkWorldSize(header.Width, header.Height); err != nil {
+		return header, fmt.Errorf("invalid checkpoint size: %v", err)
+	}
+	if header.Rule != rule {
+		return header, fmt.Errorf("checkpoint uses rule %v, only %v is supported", header.Rule, rule)
+	}
+	return header, nil
+}
+
+// readCheckpoint reads a gzip compressed checkpoint and verifies its checksum.
+func readCheckpoint(r io.Reader) (checkpointHeader, [][]byte, error) {
+	compressed, err := gzip.NewReader(r)
+	if err != nil {
+		return checkpointHeader{}, nil, err
+	}
+	defer compressed.Close()
+
+	reader := bufio.NewReader(compressed)
+	header, err := readCheckpointHeader(reader)
+	if err != nil {
+		return header, nil, err
+	}
+
+	// The world is read without allocating it up front, so a header claiming a huge world
+	// cannot cause a huge allocation before the data runs out.
+	size := (header.Width*header.Height + 7) / 8
+	packed, err := ioutil.ReadAll(io.LimitReader(reader, int64(size)+1))
+	if err != nil {
+		return header, nil, fmt.Errorf("truncated checkpoint: %v", err)
+	}
+	if len(packed) != size {
+		return header, nil, fmt.Errorf("checkpoint holds %v bytes of cells, but %vx%v needs %v",
+			len(packed), header.Width, header.Height, size)
+	}
+	if crc32.ChecksumIEEE(packed) != header.Checksum {
+		return header, nil, fmt.Errorf("checkpoint checksum mismatch")
+	}
+	return header, unpackWorld(packed, header.Width, header.Height), nil
+}
+
+// CheckpointInfo returns the turn a checkpoint was taken after and the dimensions of its world.
+func CheckpointInfo(filename string) (turn, width, height int, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer file.Close()
+
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("%v: %w", filename, err)
+	}
+	defer compressed.Close()
+
+	header, err := readCheckpointHeader(bufio.NewReader(compressed))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("%v: %w", filename, err)
+	}
+	return header.Turn, header.Width, header.Height, nil
+}
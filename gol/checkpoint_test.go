@@ -0,0 +1,126 @@
+package gol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestCheckpointRoundTrip checks that a world whose size is not a multiple of 8 survives a write and read.
+func TestCheckpointRoundTrip(t *testing.T) {
+	world := benchmarkWorld(13, 7)
+	header := checkpointHeader{Turn: 42, Width: 13, Height: 7, Turns: 100, Threads: 4, Rule: rule}
+
+	var buffer bytes.Buffer
+	if err := writeCheckpoint(&buffer, header, world); err != nil {
+		t.Fatal(err)
+	}
+
+	read, readWorld, err := readCheckpoint(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Turn != 42 || read.Width != 13 || read.Height != 7 || read.Turns != 100 || read.Threads != 4 {
+		t.Errorf("header changed: %+v", read)
+	}
+	if !reflect.DeepEqual(readWorld, world) {
+		t.Errorf("world changed after round trip")
+	}
+}
+
+// TestCheckpointChecksum checks that a corrupted world is rejected.
+func TestCheckpointChecksum(t *testing.T) {
+	var buffer bytes.Buffer
+	header := checkpointHeader{Width: 16, Height: 16, Rule: rule}
+	if err := writeCheckpoint(&buffer, header, benchmarkWorld(16, 16)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := gzip.NewReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0x01
+
+	var corrupted bytes.Buffer
+	writer := gzip.NewWriter(&corrupted)
+	_, _ = writer.Write(data)
+	_ = writer.Close()
+
+	if _, _, err := readCheckpoint(&corrupted); err == nil {
+		t.Error("expected a checksum error")
+	}
+}
+
+// TestResume checks that resuming from a checkpoint continues the turn count and reaches the same world.
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	p := Params{
+		Turns:           100,
+		Threads:         4,
+		InputFile:       filepath.Join("..", "images", "64x64.pgm"),
+		OutputDir:       dir,
+		OutputTemplate:  "{turn}",
+		CheckpointEvery: 50,
+	}
+	expected, _ := runToCompletion(p)
+
+	p.InputFile = ""
+	p.CheckpointEvery = 0
+	p.ResumeFile = filepath.Join(dir, "50"+checkpointExtension)
+	resumed, firstTurn := runToCompletion(p)
+
+	if firstTurn != 51 {
+		t.Errorf("expected the first resumed turn to be 51, got %v", firstTurn)
+	}
+	if expected.CompletedTurns != 100 || resumed.CompletedTurns != 100 {
+		t.Errorf("expected both runs to finish at turn 100, got %v and %v", expected.CompletedTurns, resumed.CompletedTurns)
+	}
+	if !reflect.DeepEqual(resumed.Alive, expected.Alive) {
+		t.Errorf("resumed run finished with %v alive cells, expected %v", len(resumed.Alive), len(expected.Alive))
+	}
+}
+
+// runToCompletion runs the Game of Life and returns its final event and the turn of its first TurnComplete.
+func runToCompletion(p Params) (FinalTurnComplete, int) {
+	events := make(chan Event, 1000)
+	go Run(p, events, nil)
+	var final FinalTurnComplete
+	firstTurn := -1
+	for event := range events {
+		switch e := event.(type) {
+		case TurnComplete:
+			if firstTurn < 0 {
+				firstTurn = e.CompletedTurns
+			}
+		case FinalTurnComplete:
+			final = e
+		}
+	}
+	return final, firstTurn
+}
+
+// TestCheckpointSize checks that a header claiming a world too large, or larger than the data that follows it,
+// is rejected.
+func TestCheckpointSize(t *testing.T) {
+	for _, header := range []checkpointHeader{
+		{Width: 1 << 20, Height: 1 << 20, Rule: rule},
+		{Width: 1 << 62, Height: 4, Rule: rule},
+		{Width: 4096, Height: 4096, Rule: rule},
+	} {
+		var buffer bytes.Buffer
+		if err := writeCheckpoint(&buffer, header, benchmarkWorld(16, 16)); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readCheckpoint(&buffer); err == nil {
+			t.Errorf("expected a %vx%v header with a 16x16 world to be rejected", header.Width, header.Height)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package gol
+
+import (
+	"fmt"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Command is a typed request to a running Game of Life, sent on the channel given to RunCommands.
+// Every Command has an optional Ack channel. If it is not nil, the distributor sends exactly one Ack
+// on it once the command has taken effect, so it should have room for one value.
+type Command interface {
+	acknowledge(turn int, err error)
+}
+
+// Ack acknowledges a Command. Err explains why the command could not be carried out.
+type Ack struct {
+	CompletedTurns int
+	Err            error
+}
+
+// Pause stops executing turns until Resume is sent.
+type Pause struct {
+	Ack chan<- Ack
+}
+
+// Resume continues executing turns after Pause.
+type Resume struct {
+	Ack chan<- Ack
+}
+
+// Snapshot writes the current world as a pgm image. Name replaces Params.OutputTemplate if it is set.
+// The Ack is sent once the image is queued, and ImageOutputComplete follows once it is written.
+type Snapshot struct {
+	Name string
+	Ack  chan<- Ack
+}
+
+// Checkpoint writes the current world as a checkpoint that can be resumed from.
+// Name replaces Params.OutputTemplate if it is set.
+type Checkpoint struct {
+	Name string
+	Ack  chan<- Ack
+}
+
+// Quit stops the run early, reporting and saving the final world as if the last turn had been reached.
+// The Ack is sent once all output has been written.
+type Quit struct {
+	Ack chan<- Ack
+}
+
+// Kill shuts down every component of the run. In a single process this is the same as Quit.
+type Kill struct {
+	Ack chan<- Ack
+}
+
+// Step executes exactly N turns while paused, then stays paused. The Ack is sent after the last of them.
+type Step struct {
+	N   int
+	Ack chan<- Ack
+}
+
+// SetSpeed limits execution to TPS turns per second. Zero removes the limit.
+type SetSpeed struct {
+	TPS float64
+	Ack chan<- Ack
+}
+
+// Edit makes each of Cells alive, or dead if Alive is false, while paused.
+// The cells that change are reported in a CellsFlipped event with Turns 0, followed by TurnComplete for the current turn.
+type Edit struct {
+	Cells []util.Cell
+	Alive bool
+	Ack   chan<- Ack
+}
+
+// Undo reverts the last Edit. Edits can be undone one by one until the next turn is executed.
+type Undo struct {
+	Ack chan<- Ack
+}
+
+func sendAck(ack chan<- Ack, turn int, err error) {
+	if ack != nil {
+		ack <- Ack{turn, err}
+	}
+}
+
+func (command Pause) acknowledge(turn int, err error)      { sendAck(command.Ack, turn, err) }
+func (command Resume) acknowledge(turn int, err error)     { sendAck(command.Ack, turn, err) }
+func (command Snapshot) acknowledge(turn int, err error)   { sendAck(command.Ack, turn, err) }
+func (command Checkpoint) acknowledge(turn int, err error) { sendAck(command.Ack, turn, err) }
+func (command Quit) acknowledge(turn int, err error)       { sendAck(command.Ack, turn, err) }
+func (command Kill) acknowledge(turn int, err error)       { sendAck(command.Ack, turn, err) }
+func (command Step) acknowledge(turn int, err error)       { sendAck(command.Ack, turn, err) }
+func (command SetSpeed) acknowledge(turn int, err error)   { sendAck(command.Ack, turn, err) }
+func (command Edit) acknowledge(turn int, err error)       { sendAck(command.Ack, turn, err) }
+func (command Undo) acknowledge(turn int, err error)       { sendAck(command.Ack, turn, err) }
+
+// keySpeeds are the turns per second that '-' and '+' move between. The last one, zero, is full speed.
+var keySpeeds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 0}
+
+// Keys translates the single-key controls of the SDL window into Commands.
+// 'p' alternates between Pause and Resume, 'n' steps one turn while paused, 'u' undoes the last edit,
+// and '-' and '+' move down and up keySpeeds starting from full speed.
+type Keys struct {
+	paused bool
+	speed  int
+}
+
+// NewKeys returns a Keys for a run that is executing at full speed.
+func NewKeys() *Keys {
+	return &Keys{speed: len(keySpeeds) - 1}
+}
+
+// Command returns the Command for key, or nil if key does nothing.
+func (k *Keys) Command(key rune) Command {
+	switch key {
+	case 'p':
+		k.paused = !k.paused
+		if k.paused {
+			return Pause{}
+		}
+		return Resume{}
+	case 's':
+		return Snapshot{}
+	case 'c':
+		return Checkpoint{}
+	case 'q':
+		return Quit{}
+	case 'k':
+		return Kill{}
+	case 'n':
+		return Step{N: 1}
+	case 'u':
+		return Undo{}
+	case '-':
+		if k.speed > 0 {
+			k.speed--
+		}
+		return SetSpeed{TPS: keySpeeds[k.speed]}
+	case '+':
+		if k.speed < len(keySpeeds)-1 {
+			k.speed++
+		}
+		return SetSpeed{TPS: keySpeeds[k.speed]}
+	}
+	return nil
+}
+
+// keyCommands translates key presses into Commands with Keys until done is closed.
+func keyCommands(keyPresses <-chan rune, commands chan<- Command, done <-chan struct{}) {
+	keys := NewKeys()
+	for {
+		var key rune
+		select {
+		case key = <-keyPresses:
+		case <-done:
+			return
+		}
+
+		command := keys.Command(key)
+		if command == nil {
+			continue
+		}
+
+		select {
+		case commands <- command:
+		case <-done:
+			return
+		}
+	}
+}
+
+// handle carries out a command between turns.
+// It only returns an error if the run has stopped; problems with the command itself are acknowledged.
+func (s *distributorState) handle(command Command) error {
+	switch command := command.(type) {
+	case Pause:
+		if s.paused {
+			command.acknowledge(s.turn, fmt.Errorf("already paused"))
+			return nil
+		}
+		s.paused = true
+		if err := s.sendState(); err != nil {
+			return err
+		}
+		command.acknowledge(s.turn, nil)
+	case Resume:
+		if !s.paused {
+			command.acknowledge(s.turn, fmt.Errorf("not paused"))
+			return nil
+		}
+		s.paused = false
+		if err := s.sendState(); err != nil {
+			return err
+		}
+		command.acknowledge(s.turn, nil)
+	case Snapshot:
+		filename := outputFilename(s.p, s.turn)
+		if command.Name != "" {
+			filename = expandTemplate(s.p, command.Name, s.turn)
+		}
+		if escapesOutputDir(filename) {
+			command.acknowledge(s.turn, fmt.Errorf("snapshot name %q escapes the output directory", command.Name))
+			return nil
+		}
+		if err := writeImage(s.p, s.c, s.world, s.turn, filename); err != nil {
+			return err
+		}
+		command.acknowledge(s.turn, nil)
+	case Checkpoint:
+		filename := outputFilename(s.p, s.turn)
+		if command.Name != "" {
+			filename = expandTemplate(s.p, command.Name, s.turn)
+		}
+		if escapesOutputDir(filename) {
+			command.acknowledge(s.turn, fmt.Errorf("checkpoint name %q escapes the output directory", command.Name))
+			return nil
+		}
+		if err := saveCheckpoint(s.p, s.c, s.world, s.turn, filename); err != nil {
+			return err
+		}
+		command.acknowledge(s.turn, nil)
+	case Quit, Kill:
+		s.quit = command
+	case Step:
+		switch {
+		case !s.paused:
+			command.acknowledge(s.turn, fmt.Errorf("can only step while paused"))
+		case command.N < 1:
+			command.acknowledge(s.turn, fmt.Errorf("cannot step %v turns", command.N))
+		case s.step != nil:
+			command.acknowledge(s.turn, fmt.Errorf("already stepping"))
+		default:
+			s.step = command
+			s.steps = command.N
+			return s.sendState()
+		}
+	case SetSpeed:
+		if command.TPS < 0 {
+			command.acknowledge(s.turn, fmt.Errorf("cannot run at %v turns per second", command.TPS))
+			return nil
+		}
+		s.speed = command.TPS
+		s.interval = 0
+		if command.TPS > 0 {
+			s.interval = time.Duration(float64(time.Second) / command.TPS)
+		}
+		if err := s.sendState(); err != nil {
+			return err
+		}
+		command.acknowledge(s.turn, nil)
+	case Edit:
+		if !s.paused {
+			command.acknowledge(s.turn, fmt.Errorf("can only edit while paused"))
+			return nil
+		}
+		for _, cell := range command.Cells {
+			if cell.X < 0 || cell.Y < 0 || cell.X >= s.Width() || cell.Y >= s.Height() {
+				command.acknowledge(s.turn, fmt.Errorf("cell (%v, %v) is outside the world", cell.X, cell.Y))
+				return nil
+			}
+		}
+		var changed []util.Cell
+		for _, cell := range command.Cells {
+			if s.Alive(cell.X, cell.Y) != command.Alive {
+				s.Set(cell.X, cell.Y, command.Alive)
+				changed = append(changed, cell)
+			}
+		}
+		if len(changed) > 0 {
+			s.edits = append(s.edits, changed)
+			if err := s.sendEdits(changed); err != nil {
+				return err
+			}
+		}
+		command.acknowledge(s.turn, nil)
+	case Undo:
+		if len(s.edits) == 0 {
+			command.acknowledge(s.turn, fmt.Errorf("nothing to undo"))
+			return nil
+		}
+		changed := s.edits[len(s.edits)-1]
+		s.edits = s.edits[:len(s.edits)-1]
+		for _, cell := range changed {
+			s.Set(cell.X, cell.Y, !s.Alive(cell.X, cell.Y))
+		}
+		if err := s.sendEdits(changed); err != nil {
+			return err
+		}
+		command.acknowledge(s.turn, nil)
+	}
+	return nil
+}
+
+// sendEdits reports cells changed by Edit or Undo, then TurnComplete so that the window is redrawn.
+// Every changed cell has flipped, so the world can be put back as it was to show any turns not yet shown first.
+func (s *distributorState) sendEdits(changed []util.Cell) error {
+	if s.p.ReportStatistics {
+		s.population = s.Population()
+	}
+	if s.p.FrameRate > 0 {
+		flipCells(s.world, changed)
+		err := s.sendFrame()
+		flipCells(s.world, changed)
+		if err != nil {
+			return err
+		}
+		flipCells(s.shown, changed)
+	}
+	if err := s.c.send(CellsFlipped{CompletedTurns: s.turn, Cells: changed}); err != nil {
+		return err
+	}
+	return s.c.send(TurnComplete{s.turn})
+}
+
+// flipCells makes each of cells in world dead if it is alive and alive if it is dead.
+func flipCells(world [][]byte, cells []util.Cell) {
+	for _, cell := range cells {
+		if world[cell.Y][cell.X] == aliveCell {
+			world[cell.Y][cell.X] = deadCell
+		} else {
+			world[cell.Y][cell.X] = aliveCell
+		}
+	}
+}
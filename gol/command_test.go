@@ -0,0 +1,327 @@
+package gol
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// startCommands runs a 16x16 world for p.Turns turns in the background, controlled by the returned channel.
+// The events of the run are collected and sent on the second channel once it finishes.
+func startCommands(t *testing.T, p Params) (chan<- Command, <-chan []Event) {
+	p.Threads = 2
+	p.InputFile = filepath.Join("..", "images", "16x16.pgm")
+	p.OutputDir = t.TempDir()
+
+	commands := make(chan Command)
+	events := make(chan Event, 1000)
+	collected := make(chan []Event, 1)
+	go RunCommands(p, events, commands)
+	go func() {
+		var all []Event
+		for event := range events {
+			all = append(all, event)
+		}
+		collected <- all
+	}()
+	return commands, collected
+}
+
+// send sends a Command built around a new Ack channel and waits for its acknowledgement.
+func send(t *testing.T, commands chan<- Command, command func(chan<- Ack) Command) Ack {
+	ack := make(chan Ack, 1)
+	commands <- command(ack)
+	select {
+	case a := <-ack:
+		return a
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not acknowledged")
+		return Ack{}
+	}
+}
+
+// TestPauseStepResume checks that Step advances a paused world by exactly N turns.
+func TestPauseStepResume(t *testing.T) {
+	commands, collected := startCommands(t, Params{Turns: 1 << 30})
+
+	paused := send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} })
+	if paused.Err != nil {
+		t.Fatal(paused.Err)
+	}
+	if again := send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} }); again.Err == nil {
+		t.Error("expected pausing twice to fail")
+	}
+
+	stepped := send(t, commands, func(ack chan<- Ack) Command { return Step{3, ack} })
+	if stepped.Err != nil {
+		t.Fatal(stepped.Err)
+	}
+	if stepped.CompletedTurns != paused.CompletedTurns+3 {
+		t.Errorf("expected step to reach turn %v, got %v", paused.CompletedTurns+3, stepped.CompletedTurns)
+	}
+	if zero := send(t, commands, func(ack chan<- Ack) Command { return Step{0, ack} }); zero.Err == nil {
+		t.Error("expected stepping 0 turns to fail")
+	}
+
+	if resumed := send(t, commands, func(ack chan<- Ack) Command { return Resume{ack} }); resumed.Err != nil {
+		t.Fatal(resumed.Err)
+	}
+	if step := send(t, commands, func(ack chan<- Ack) Command { return Step{1, ack} }); step.Err == nil {
+		t.Error("expected stepping while executing to fail")
+	}
+
+	quit := send(t, commands, func(ack chan<- Ack) Command { return Quit{ack} })
+	var states []State
+	for _, event := range <-collected {
+		switch e := event.(type) {
+		case StateChange:
+			states = append(states, e.NewState)
+		case FinalTurnComplete:
+			if e.CompletedTurns != quit.CompletedTurns {
+				t.Errorf("final turn %v does not match quit acknowledgement %v", e.CompletedTurns, quit.CompletedTurns)
+			}
+		}
+	}
+	expected := []State{Executing, Paused, Stepping, Paused, Executing, Quitting}
+	if len(states) != len(expected) {
+		t.Fatalf("expected states %v, got %v", expected, states)
+	}
+	for i := range states {
+		if states[i] != expected[i] {
+			t.Errorf("expected states %v, got %v", expected, states)
+			break
+		}
+	}
+}
+
+// TestSnapshotName checks that a named snapshot is written to the output directory and rejected if it escapes it.
+func TestSnapshotName(t *testing.T) {
+	commands, collected := startCommands(t, Params{Turns: 1 << 30})
+
+	send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} })
+	if escaped := send(t, commands, func(ack chan<- Ack) Command { return Snapshot{"../outside", ack} }); escaped.Err == nil {
+		t.Error("expected a snapshot outside the output directory to fail")
+	}
+	snapshot := send(t, commands, func(ack chan<- Ack) Command { return Snapshot{"named-{turn}", ack} })
+	if snapshot.Err != nil {
+		t.Fatal(snapshot.Err)
+	}
+	send(t, commands, func(ack chan<- Ack) Command { return Kill{ack} })
+
+	found := false
+	for _, event := range <-collected {
+		if e, ok := event.(ImageOutputComplete); ok && e.Filename == expandTemplate(Params{}, "named-{turn}", snapshot.CompletedTurns) {
+			found = true
+			if _, err := os.Stat(e.Path); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	if !found {
+		t.Error("named snapshot was not reported")
+	}
+}
+
+// TestEditAfterSnapshot checks that an Edit sent straight after a Snapshot waits for the world to be copied,
+// so the snapshot does not race with the edit or include it. Run with -race.
+func TestEditAfterSnapshot(t *testing.T) {
+	commands, collected := startCommands(t, Params{Turns: 1 << 30})
+	lastRow := make([]util.Cell, 16)
+	for x := range lastRow {
+		lastRow[x] = util.Cell{X: x, Y: 15}
+	}
+
+	send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} })
+	send(t, commands, func(ack chan<- Ack) Command { return Edit{lastRow, false, ack} })
+	snapshot := send(t, commands, func(ack chan<- Ack) Command { return Snapshot{"edited", ack} })
+	send(t, commands, func(ack chan<- Ack) Command { return Edit{lastRow, true, ack} })
+	send(t, commands, func(ack chan<- Ack) Command { return Kill{ack} })
+
+	var path string
+	for _, event := range <-collected {
+		if e, ok := event.(ImageOutputComplete); ok && e.Filename == "edited" {
+			path = e.Path
+		}
+	}
+	if path == "" {
+		t.Fatalf("snapshot at turn %v was not reported", snapshot.CompletedTurns)
+	}
+	world, _, err := ReadWorld(path, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x, cell := range world[15] {
+		if cell != deadCell {
+			t.Fatalf("expected the snapshot to be taken before the last row was made alive, but (%v, 15) is alive", x)
+		}
+	}
+}
+
+// TestCheckpointCommand checks that a checkpoint requested by command is written and resumes to the same world
+// as a run that never stopped.
+func TestCheckpointCommand(t *testing.T) {
+	commands, collected := startCommands(t, Params{Turns: 1 << 30})
+
+	send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} })
+	// Make sure the checkpoint is not of the initial world.
+	send(t, commands, func(ack chan<- Ack) Command { return Step{5, ack} })
+	checkpoint := send(t, commands, func(ack chan<- Ack) Command { return Checkpoint{"saved-{turn}", ack} })
+	if checkpoint.Err != nil {
+		t.Fatal(checkpoint.Err)
+	}
+	send(t, commands, func(ack chan<- Ack) Command { return Kill{ack} })
+
+	var path string
+	for _, event := range <-collected {
+		if e, ok := event.(ImageOutputComplete); ok && e.Filename == expandTemplate(Params{}, "saved-{turn}", checkpoint.CompletedTurns) {
+			path = e.Path
+		}
+	}
+	if filepath.Ext(path) != checkpointExtension {
+		t.Fatalf("expected the checkpoint to be reported with a %v path, got %q", checkpointExtension, path)
+	}
+	turn, _, _, err := CheckpointInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn != checkpoint.CompletedTurns {
+		t.Errorf("expected the checkpoint to be at turn %v, got %v", checkpoint.CompletedTurns, turn)
+	}
+
+	p := Params{
+		Turns:     checkpoint.CompletedTurns + 10,
+		Threads:   2,
+		InputFile: filepath.Join("..", "images", "16x16.pgm"),
+		OutputDir: t.TempDir(),
+	}
+	expected, _ := runToCompletion(p)
+	p.InputFile = ""
+	p.ResumeFile = path
+	resumed, _ := runToCompletion(p)
+	if !reflect.DeepEqual(resumed.Alive, expected.Alive) {
+		t.Errorf("resumed run finished with %v alive cells, expected %v", len(resumed.Alive), len(expected.Alive))
+	}
+}
+
+// TestSetSpeed checks that SetSpeed holds turns back to the requested rate and is reported in StateChange.
+func TestSetSpeed(t *testing.T) {
+	const turns = 10
+	commands, collected := startCommands(t, Params{Turns: 1 << 30})
+
+	send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} })
+	if negative := send(t, commands, func(ack chan<- Ack) Command { return SetSpeed{-1, ack} }); negative.Err == nil {
+		t.Error("expected a negative speed to fail")
+	}
+	send(t, commands, func(ack chan<- Ack) Command { return SetSpeed{100, ack} })
+
+	start := time.Now()
+	send(t, commands, func(ack chan<- Ack) Command { return Step{turns, ack} })
+	if elapsed := time.Since(start); elapsed < (turns-1)*10*time.Millisecond {
+		t.Errorf("%v turns at 100 turns per second took %v", turns, elapsed)
+	}
+
+	send(t, commands, func(ack chan<- Ack) Command { return Resume{ack} })
+	send(t, commands, func(ack chan<- Ack) Command { return Quit{ack} })
+	throttled := false
+	for _, event := range <-collected {
+		if e, ok := event.(StateChange); ok && e.NewState == Throttled && e.TurnsPerSecond == 100 {
+			throttled = true
+		}
+	}
+	if !throttled {
+		t.Error("resuming with a speed limit was not reported as Throttled")
+	}
+}
+
+// TestKeyCommands checks that the SDL keys are translated into Commands.
+func TestKeyCommands(t *testing.T) {
+	keyPresses := make(chan rune, 20)
+	commands := make(chan Command, 20)
+	done := make(chan struct{})
+	defer close(done)
+	go keyCommands(keyPresses, commands, done)
+
+	for _, key := range "xpspcqkn-+++" {
+		keyPresses <- key
+	}
+	expected := []Command{Pause{}, Snapshot{}, Resume{}, Checkpoint{}, Quit{}, Kill{}, Step{N: 1},
+		SetSpeed{TPS: 1000}, SetSpeed{TPS: 0}, SetSpeed{TPS: 0}}
+	for _, want := range expected {
+		if got := <-commands; got != want {
+			t.Errorf("expected %#v, got %#v", want, got)
+		}
+	}
+}
+
+// TestEditUndo checks that edits are only made while paused, are reported as flips of no turns, and can be undone until the next turn.
+// With a frame rate, the edits must not be merged with turns that have not been shown yet.
+func TestEditUndo(t *testing.T) {
+	t.Run("every turn", func(t *testing.T) {
+		testEditUndo(t, Params{Turns: 1 << 30})
+	})
+	t.Run("frame rate", func(t *testing.T) {
+		testEditUndo(t, Params{Turns: 1 << 30, FrameRate: 1})
+	})
+}
+
+func testEditUndo(t *testing.T, p Params) {
+	commands, collected := startCommands(t, p)
+	corner := []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}}
+
+	if executing := send(t, commands, func(ack chan<- Ack) Command { return Edit{corner, true, ack} }); executing.Err == nil {
+		t.Error("expected editing while executing to fail")
+	}
+	paused := send(t, commands, func(ack chan<- Ack) Command { return Pause{ack} })
+	outside := []util.Cell{{X: 16, Y: 0}}
+	if escaped := send(t, commands, func(ack chan<- Ack) Command { return Edit{outside, true, ack} }); escaped.Err == nil {
+		t.Error("expected editing a cell outside the world to fail")
+	}
+	if empty := send(t, commands, func(ack chan<- Ack) Command { return Undo{ack} }); empty.Err == nil {
+		t.Error("expected undo with no edits to fail")
+	}
+
+	send(t, commands, func(ack chan<- Ack) Command { return Edit{corner, true, ack} })
+	send(t, commands, func(ack chan<- Ack) Command { return Edit{corner[:1], false, ack} })
+	if undone := send(t, commands, func(ack chan<- Ack) Command { return Undo{ack} }); undone.Err != nil {
+		t.Fatal(undone.Err)
+	}
+	send(t, commands, func(ack chan<- Ack) Command { return Step{1, ack} })
+	if stale := send(t, commands, func(ack chan<- Ack) Command { return Undo{ack} }); stale.Err == nil {
+		t.Error("expected undo after a turn to fail")
+	}
+	send(t, commands, func(ack chan<- Ack) Command { return Kill{ack} })
+
+	// Collect the flips between pausing and stepping. The 16x16 image has no alive cells in its top row,
+	// so each edit flips the cells it makes alive and each undo flips them back.
+	var flipped []util.Cell
+	completed := 0
+	editing, edited := false, false
+	for _, event := range <-collected {
+		switch e := event.(type) {
+		case StateChange:
+			editing = e.NewState == Paused && e.CompletedTurns == paused.CompletedTurns
+		case CellsFlipped:
+			// With a frame rate, the turns run before pausing are shown first.
+			if editing && e.Turns == 0 {
+				flipped = append(flipped, e.Cells...)
+				edited = true
+			}
+		case TurnComplete:
+			if edited {
+				completed++
+				edited = false
+			}
+		}
+	}
+	expected := []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 0}, {X: 0, Y: 0}}
+	if !reflect.DeepEqual(flipped, expected) {
+		t.Errorf("expected flips %v while paused, got %v", expected, flipped)
+	}
+	if completed != 3 {
+		t.Errorf("expected TurnComplete after each of 3 edits, got %v", completed)
+	}
+}
@@ -0,0 +1,184 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadWorld loads a world from a pgm, png, jpeg, gif, checkpoint, macrocell or RLE file, chosen by extension.
+// Images are read at their own size and converted with threshold (zero means 128) or dithering.
+// It also returns the turn the world was saved at, which is only non-zero for checkpoints and macrocells.
+func ReadWorld(filename string, threshold uint8, dither bool) ([][]byte, int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	world, turn, err := readWorld(file, filename, threshold, dither)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%v: %v", filename, err)
+	}
+	return world, turn, nil
+}
+
+func readWorld(r io.Reader, filename string, threshold uint8, dither bool) ([][]byte, int, error) {
+	switch {
+	case isPgmFile(filename):
+		reader := bufio.NewReader(r)
+		width, height, maxval, err := readPgmHeader(reader)
+		if err != nil {
+			return nil, 0, err
+		}
+		if maxval != 255 {
+			return nil, 0, fmt.Errorf("maxval is %v, expected 255", maxval)
+		}
+		world := make([][]byte, height)
+		for y := range world {
+			world[y] = make([]byte, width)
+			if err := readPgmRow(reader, world[y]); err != nil {
+				return nil, 0, err
+			}
+		}
+		return world, 0, nil
+	case isCheckpointFile(filename):
+		header, world, err := readCheckpoint(r)
+		return world, header.Turn, err
+	case isMacrocellFile(filename):
+		pattern, err := readMacrocell(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		world, err := pattern.materialise(pattern.size(), pattern.size())
+		return world, pattern.generation, err
+	case isRLEFile(filename):
+		world, err := readRLE(r)
+		return world, 0, err
+	default:
+		img, _, err := image.Decode(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		limit := float64(threshold)
+		if limit == 0 {
+			limit = defaultImportThreshold
+		}
+		brightness := scaleToGrey(img, img.Bounds().Dx(), img.Bounds().Dy())
+		if dither {
+			return ditherCells(brightness, limit), 0, nil
+		}
+		return thresholdCells(brightness, limit), 0, nil
+	}
+}
+
+// WriteWorld saves the world as a pgm, png, checkpoint, macrocell or RLE file, chosen by extension.
+// turn is recorded by the formats that have a place for it.
+func WriteWorld(filename string, world [][]byte, turn int) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := writeWorld(file, filename, world, turn); err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	return file.Close()
+}
+
+func writeWorld(w io.Writer, filename string, world [][]byte, turn int) error {
+	width, height := worldSize(world)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pgm":
+		return writePgm(w, world)
+	case ".png":
+		img := image.NewGray(image.Rect(0, 0, width, height))
+		for y, row := range world {
+			copy(img.Pix[y*img.Stride:], row)
+		}
+		return png.Encode(w, img)
+	case checkpointExtension:
+		return writeCheckpoint(w, checkpointHeader{Turn: turn, Width: width, Height: height, Rule: rule}, world)
+	case macrocellExtension:
+		return writeMacrocell(w, newMacrocell(world, turn))
+	case rleExtension:
+		return writeRLE(w, world)
+	default:
+		return fmt.Errorf("unsupported output format %q", filepath.Ext(filename))
+	}
+}
+
+// writePgm writes the world as a binary pgm image.
+func writePgm(w io.Writer, world [][]byte) error {
+	width, height := worldSize(world)
+
+	writer := bufio.NewWriter(w)
+	_, _ = writer.WriteString("P5\n")
+	//_, _ = writer.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
+	_, _ = writer.WriteString(strconv.Itoa(width))
+	_, _ = writer.WriteString(" ")
+	_, _ = writer.WriteString(strconv.Itoa(height))
+	_, _ = writer.WriteString("\n")
+	_, _ = writer.WriteString(strconv.Itoa(255))
+	_, _ = writer.WriteString("\n")
+
+	for _, row := range world {
+		if _, err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// worldSize returns the width and height of the world.
+func worldSize(world [][]byte) (width, height int) {
+	if len(world) == 0 {
+		return 0, 0
+	}
+	return len(world[0]), len(world)
+}
+
+// CropWorld returns the width x height region of the world whose top left corner is at (x, y).
+// Parts of the region outside the world are dead.
+func CropWorld(world [][]byte, x, y, width, height int) [][]byte {
+	cropped := makeWorld(height, width)
+	for cy := range cropped {
+		if y+cy < 0 || y+cy >= len(world) {
+			continue
+		}
+		row := world[y+cy]
+		for cx := range cropped[cy] {
+			if x+cx >= 0 && x+cx < len(row) {
+				cropped[cy][cx] = row[x+cx]
+			}
+		}
+	}
+	return cropped
+}
+
+// ResizeWorld pads or crops the world to width x height, keeping it centred if centre is set
+// and anchored at the top left otherwise.
+func ResizeWorld(world [][]byte, width, height int, centre bool) [][]byte {
+	if !centre {
+		return CropWorld(world, 0, 0, width, height)
+	}
+	oldWidth, oldHeight := worldSize(world)
+	return CropWorld(world, (oldWidth-width)/2, (oldHeight-height)/2, width, height)
+}
+
+// EvolveWorld returns the world after the given number of generations, computed on threads workers.
+func EvolveWorld(world [][]byte, generations, threads int) [][]byte {
+	simulation := NewSimulationFromWorld(world, 0, threads)
+	simulation.Step(generations)
+	return simulation.world
+}
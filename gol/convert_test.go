@@ -0,0 +1,76 @@
+package gol
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestConvertRoundTrip checks that every writable format reads back as the same world.
+func TestConvertRoundTrip(t *testing.T) {
+	world, turn, err := ReadWorld(filepath.Join("..", "images", "64x64.pgm"), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn != 0 || len(world) != 64 || len(world[0]) != 64 {
+		t.Fatalf("expected a 64x64 world at turn 0")
+	}
+
+	dir := t.TempDir()
+	for _, extension := range []string{".pgm", ".png", ".ckpt", ".mc", ".rle"} {
+		path := filepath.Join(dir, "world"+extension)
+		if err := WriteWorld(path, world, 7); err != nil {
+			t.Fatal(err)
+		}
+		read, readTurn, err := ReadWorld(path, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, world) {
+			t.Errorf("%v: world changed after round trip", extension)
+		}
+		if (extension == ".ckpt" || extension == ".mc") && readTurn != 7 {
+			t.Errorf("%v: expected turn 7, got %v", extension, readTurn)
+		}
+	}
+}
+
+// TestResizeWorld checks padding and cropping with and without centring.
+func TestResizeWorld(t *testing.T) {
+	world := [][]byte{{aliveCell, deadCell}, {deadCell, aliveCell}}
+
+	padded := ResizeWorld(world, 4, 4, true)
+	if padded[1][1] != aliveCell || padded[2][2] != aliveCell || len(calculateAliveCells(Params{ImageWidth: 4, ImageHeight: 4}, padded)) != 2 {
+		t.Errorf("expected the world in the middle of a 4x4 world, got %v", padded)
+	}
+
+	if cropped := ResizeWorld(padded, 2, 2, false); !reflect.DeepEqual(cropped, [][]byte{{deadCell, deadCell}, {deadCell, aliveCell}}) {
+		t.Errorf("expected the top left corner, got %v", cropped)
+	}
+	if cropped := ResizeWorld(padded, 2, 2, true); !reflect.DeepEqual(cropped, world) {
+		t.Errorf("expected the middle, got %v", cropped)
+	}
+}
+
+// TestEvolveWorld checks that evolving gives the same result as the distributor.
+func TestEvolveWorld(t *testing.T) {
+	world, _, err := ReadWorld(filepath.Join("..", "images", "16x16.pgm"), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _, err := ReadWorld(filepath.Join("..", "check", "images", "16x16x100.pgm"), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(EvolveWorld(world, 100, 4), expected) {
+		t.Errorf("16x16 world after 100 generations does not match check/images/16x16x100.pgm")
+	}
+}
+
+// TestReadWorldMaxval checks that a pgm whose alive cells are not 255 is rejected rather than read as dead.
+func TestReadWorldMaxval(t *testing.T) {
+	if _, _, err := readWorld(strings.NewReader("P5 2 1 1\n\x01\x00"), "low.pgm", 0, false); err == nil {
+		t.Error("expected a pgm with maxval 1 to be rejected")
+	}
+}
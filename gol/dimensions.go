@@ -0,0 +1,123 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxWorldCells is the most cells a world read from a file may have. Sizes given in headers are checked
+// against it before anything is allocated, so a small file cannot ask for more memory than a run could use.
+const maxWorldCells = 1 << 28
+
+// checkWorldSize fails if a width x height world is empty or has more than maxWorldCells cells.
+func checkWorldSize(width, height int) error {
+	if width < 1 || height < 1 {
+		return fmt.Errorf("world must be at least 1x1, not %vx%v", width, height)
+	}
+	if width > maxWorldCells/height {
+		return fmt.Errorf("a %vx%v world is larger than the limit of %v cells", width, height, maxWorldCells)
+	}
+	return nil
+}
+
+// isPgmFile reports whether filename should be read as a pgm image rather than imported.
+func isPgmFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".pgm")
+}
+
+// ImageDimensions reads the width and height from the header of a pgm, png, jpeg, gif
+// or RLE file without loading the pixel data. Macrocell files have no header, so their size is that of the root node.
+func ImageDimensions(filename string) (width, height int, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	switch {
+	case isPgmFile(filename):
+		width, height, _, err = readPgmHeader(bufio.NewReader(file))
+	case isMacrocellFile(filename):
+		var pattern *macrocell
+		pattern, err = readMacrocell(file)
+		if err == nil {
+			width, height = pattern.size(), pattern.size()
+		}
+	case isRLEFile(filename):
+		width, height, err = readRLEHeader(bufio.NewReader(file))
+	default:
+		var config image.Config
+		config, _, err = image.DecodeConfig(file)
+		width, height = config.Width, config.Height
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("%v: %w", filename, err)
+	}
+	return width, height, nil
+}
+
+// readPgmHeader reads the magic number, width, height and maxval of a binary pgm image,
+// leaving r positioned at the first pixel.
+func readPgmHeader(r *bufio.Reader) (width, height, maxval int, err error) {
+	magic, err := readPgmField(r)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if magic != "P5" {
+		return 0, 0, 0, fmt.Errorf("not a pgm file")
+	}
+
+	values := make([]int, 3)
+	for i := range values {
+		field, err := readPgmField(r)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		values[i], err = strconv.Atoi(field)
+		if err != nil || values[i] <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid pgm header field %q", field)
+		}
+	}
+	return values[0], values[1], values[2], nil
+}
+
+// readPgmField reads one whitespace-separated header token, skipping '#' comments.
+// The single whitespace byte that ends the token is consumed, as the pgm format requires.
+func readPgmField(r *bufio.Reader) (string, error) {
+	var field []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if len(field) > 0 {
+				return string(field), nil
+			}
+			return "", err
+		}
+		switch {
+		case b == '#' && len(field) == 0:
+			if _, err := r.ReadString('\n'); err != nil {
+				return "", err
+			}
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			if len(field) > 0 {
+				return string(field), nil
+			}
+		default:
+			field = append(field, b)
+		}
+	}
+}
+
+// readPgmRow fills row with the next len(row) pixels of a pgm image.
+func readPgmRow(r *bufio.Reader, row []byte) error {
+	if _, err := io.ReadFull(r, row); err != nil {
+		return fmt.Errorf("truncated pgm image: %v", err)
+	}
+	return nil
+}
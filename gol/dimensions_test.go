@@ -0,0 +1,33 @@
+package gol
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// TestImageDimensions checks that the size of a pgm image is read from its header.
+func TestImageDimensions(t *testing.T) {
+	width, height, err := ImageDimensions("../images/64x64.pgm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 64 || height != 64 {
+		t.Errorf("expected 64x64, got %vx%v", width, height)
+	}
+}
+
+// TestReadPgmHeaderComments checks that comments and mixed whitespace in the header are skipped.
+func TestReadPgmHeaderComments(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("P5\n# a comment\n3\t2 # another\n255\n\x00\xff"))
+	width, height, maxval, err := readPgmHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 3 || height != 2 || maxval != 255 {
+		t.Errorf("expected 3 2 255, got %v %v %v", width, height, maxval)
+	}
+	if b, _ := r.ReadByte(); b != 0x00 {
+		t.Errorf("expected the reader to be left at the first pixel, got %#x", b)
+	}
+}
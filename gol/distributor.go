@@ -1,33 +1,569 @@
 package gol
 
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
 type distributorChannels struct {
 	events     chan<- Event
 	ioCommand  chan<- ioCommand
 	ioIdle     <-chan bool
 	ioFilename chan<- string
-	ioOutput   chan<- uint8
-	ioInput    <-chan uint8
+	ioSnapshot chan<- ImageOutputComplete
+	ioOutput   chan<- []byte
+	ioInput    <-chan []byte
+	commands   <-chan Command
+
+	// done is closed when the run is cancelled or the io goroutine fails.
+	done <-chan struct{}
+}
+
+// distributorState is everything the distributor needs to execute turns and react to commands between them.
+// The world itself is a Simulation, which the distributor advances one turn at a time and reports on.
+type distributorState struct {
+	*Simulation
+	p Params
+	c distributorChannels
+
+	paused bool
+	// quit is the Quit or Kill command that stopped the run, acknowledged once all output is written.
+	quit Command
+	// step is the Step command being carried out while paused, with steps turns still to go.
+	step  Command
+	steps int
+	// speed is the limit set by SetSpeed, and interval is the minimum time between turns it allows,
+	// measured from lastTurn.
+	speed    float64
+	interval time.Duration
+	lastTurn time.Time
+
+	// edits holds the cells changed by each Edit since the last turn, for Undo.
+	edits [][]util.Cell
+
+	// population is the number of alive cells, and stats holds the Statistics not yet sent,
+	// while ReportStatistics is set.
+	population int
+	stats      []Statistics
+
+	// shown is the world as of the last frame sent while FrameRate is set, at shownTurn and lastFrame.
+	shown     [][]byte
+	shownTurn int
+	lastFrame time.Time
 }
 
 // distributor divides the work between workers and interacts with other goroutines.
-func distributor(p Params, c distributorChannels) {
+// turn is the number of turns already completed, which is only non-zero when resuming from a checkpoint.
+// It returns errStopped if done is closed before the run finishes.
+func distributor(p Params, c distributorChannels, turn int) error {
 
-	// TODO: Create a 2D slice to store the world.
+	s := distributorState{p: p, c: c}
+	err := s.run(turn)
 
-	turn := 0
+	// Commands are only received once the world has been loaded, so s.Simulation is set if either is pending.
+	if s.step != nil {
+		if err == nil {
+			err = fmt.Errorf("run finished after %v turns", s.turn)
+		}
+		s.step.acknowledge(s.turn, err)
+	}
+	if s.quit != nil {
+		s.quit.acknowledge(s.turn, err)
+	}
+	return err
+}
 
+// run loads the world, executes turns until the last one or until Quit, and saves the final world.
+func (s *distributorState) run(turn int) error {
+	p, c := s.p, s.c
 
-	// TODO: Execute all turns of the Game of Life.
+	world, err := loadWorld(p, c)
+	if err != nil {
+		return err
+	}
+	s.Simulation = newSimulation(p, world, turn)
+	if err := s.sendFlipped(s.AliveCells(), 0); err != nil {
+		return err
+	}
+	if p.FrameRate > 0 {
+		s.shown, s.shownTurn, s.lastFrame = copyWorld(s.world), s.turn, time.Now()
+	}
+	if p.ReportStatistics {
+		s.population = s.Population()
+	}
 
-	// TODO: Report the final state using FinalTurnCompleteEvent.
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
 
+	if err := s.saveTurnOutputs(); err != nil {
+		return err
+	}
+
+	if err := s.sendState(); err != nil {
+		return err
+	}
+
+	for s.quit == nil && s.turn < p.Turns {
+		wait, ready := s.untilNextTurn()
+		if !ready {
+			// Paused or held back by SetSpeed, so show the latest turn and block until something happens.
+			if err := s.sendFrame(); err != nil {
+				return err
+			}
+			var timer <-chan time.Time
+			if wait > 0 {
+				timer = time.After(wait)
+			}
+			select {
+			case command := <-c.commands:
+				err = s.handle(command)
+			case <-ticker.C:
+				err = c.send(AliveCellsCount{s.turn, s.Population()})
+			case <-timer:
+			case <-c.done:
+				err = errStopped
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case command := <-c.commands:
+			err = s.handle(command)
+		case <-ticker.C:
+			err = c.send(AliveCellsCount{s.turn, s.Population()})
+		case <-c.done:
+			err = errStopped
+		default:
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, ready := s.untilNextTurn(); ready && s.quit == nil {
+			if err := s.executeTurn(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.sendFrame(); err != nil {
+		return err
+	}
+	if err := c.send(FinalTurnComplete{s.turn, s.AliveCells()}); err != nil {
+		return err
+	}
+	if err := saveWorld(p, c, s.world, s.turn); err != nil {
+		return err
+	}
+
+	if p.VideoFile != "" {
+		if err := c.io(ioVideoClose); err != nil {
+			return err
+		}
+	}
 
 	// Make sure that the Io has finished any output before exiting.
-	c.ioCommand <- ioCheckIdle
-	<-c.ioIdle
+	if err := c.io(ioCheckIdle); err != nil {
+		return err
+	}
+	select {
+	case <-c.ioIdle:
+	case <-c.done:
+		return errStopped
+	}
+
+	return c.send(StateChange{s.turn, Quitting, s.speed})
+}
+
+// untilNextTurn reports whether a turn can be executed now. If SetSpeed is holding the next turn back,
+// it also returns how long is left to wait.
+func (s *distributorState) untilNextTurn() (time.Duration, bool) {
+	if s.paused && s.steps == 0 {
+		return 0, false
+	}
+	if s.interval > 0 {
+		if wait := time.Until(s.lastTurn.Add(s.interval)); wait > 0 {
+			return wait, false
+		}
+	}
+	return 0, true
+}
+
+// executeTurn evolves the world by one turn and reports the changes.
+func (s *distributorState) executeTurn() error {
+	s.lastTurn = time.Now()
+	s.edits = nil
+	if s.p.FrameRate > 0 {
+		before := s.world
+		s.Step(1)
+		if s.p.ReportStatistics {
+			s.recordStatistics(countChanges(before, s.world))
+		}
+		if s.frameDue() {
+			if err := s.sendFrame(); err != nil {
+				return err
+			}
+		}
+	} else {
+		flipped := s.advance()
+		if err := s.sendFlipped(flipped, 1); err != nil {
+			return err
+		}
+		if s.p.ReportStatistics {
+			born := 0
+			for _, cell := range flipped {
+				if s.world[cell.Y][cell.X] == aliveCell {
+					born++
+				}
+			}
+			s.recordStatistics(born, len(flipped)-born)
+			if err := s.sendStatistics(); err != nil {
+				return err
+			}
+		}
+		if err := s.c.send(TurnComplete{s.turn}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.saveTurnOutputs(); err != nil {
+		return err
+	}
+	if s.p.CheckpointEvery > 0 && s.turn%s.p.CheckpointEvery == 0 {
+		if err := saveCheckpoint(s.p, s.c, s.world, s.turn, outputFilename(s.p, s.turn)); err != nil {
+			return err
+		}
+	}
+
+	if s.steps > 0 {
+		s.steps--
+		if s.steps == 0 {
+			s.step.acknowledge(s.turn, nil)
+			s.step = nil
+			return s.sendState()
+		}
+	}
+	return nil
+}
+
+// currentState is the State of a run that has not finished.
+func (s *distributorState) currentState() State {
+	switch {
+	case s.paused && s.steps > 0:
+		return Stepping
+	case s.paused:
+		return Paused
+	case s.interval > 0:
+		return Throttled
+	default:
+		return Executing
+	}
+}
+
+// sendState reports the current state and speed limit.
+func (s *distributorState) sendState() error {
+	return s.c.send(StateChange{s.turn, s.currentState(), s.speed})
+}
+
+// sendFlipped reports the cells that changed over turns turns, in one event if BatchFlips or FrameRate is set.
+func (s *distributorState) sendFlipped(cells []util.Cell, turns int) error {
+	if s.p.BatchFlips || s.p.FrameRate > 0 {
+		if len(cells) == 0 {
+			return nil
+		}
+		return s.c.send(CellsFlipped{CompletedTurns: s.turn, Cells: cells, Turns: turns})
+	}
+	for _, cell := range cells {
+		if err := s.c.send(CellFlipped{s.turn, cell}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordStatistics adds the Statistics of the turn just executed, in which born cells came alive and died cells died.
+func (s *distributorState) recordStatistics(born, died int) {
+	s.population += born - died
+	s.stats = append(s.stats, Statistics{s.turn, s.population, born, died})
+}
+
+// sendStatistics sends the Statistics recorded since it was last called, if there are any.
+func (s *distributorState) sendStatistics() error {
+	if len(s.stats) == 0 {
+		return nil
+	}
+	stats := s.stats
+	s.stats = nil
+	return s.c.send(TurnStatistics{s.turn, stats})
+}
+
+// countChanges returns the number of cells that are alive in after but not before, and the other way round.
+func countChanges(before, after [][]byte) (born, died int) {
+	for y, row := range after {
+		for x, cell := range row {
+			if cell != before[y][x] {
+				if cell == aliveCell {
+					born++
+				} else {
+					died++
+				}
+			}
+		}
+	}
+	return born, died
+}
+
+// frameDue reports whether it is time for the next frame and the visualiser has read the previous one.
+func (s *distributorState) frameDue() bool {
+	return time.Since(s.lastFrame) >= time.Duration(float64(time.Second)/s.p.FrameRate) && len(s.c.events) == 0
+}
+
+// sendFrame sends every cell that changed since the last frame, then TurnComplete.
+// It does nothing unless FrameRate is set and a turn has completed since the last frame.
+func (s *distributorState) sendFrame() error {
+	if s.p.FrameRate <= 0 || s.shownTurn == s.turn {
+		return nil
+	}
+	flipped := diffWorlds(s.p, s.shown, s.world)
+	for y, row := range s.world {
+		copy(s.shown[y], row)
+	}
+	turns := s.turn - s.shownTurn
+	s.shownTurn, s.lastFrame = s.turn, time.Now()
+
+	if len(flipped) > 0 {
+		if err := s.c.send(CellsFlipped{CompletedTurns: s.turn, Cells: flipped, Turns: turns}); err != nil {
+			return err
+		}
+	}
+	if err := s.sendStatistics(); err != nil {
+		return err
+	}
+	return s.c.send(TurnComplete{s.turn})
+}
+
+// saveTurnOutputs writes the frame and video frame for the current turn, if there should be one.
+func (s *distributorState) saveTurnOutputs() error {
+	if s.p.FrameEvery > 0 && s.turn%s.p.FrameEvery == 0 {
+		if err := saveFrame(s.p, s.c, s.world, s.turn); err != nil {
+			return err
+		}
+	}
+	if isVideoTurn(s.p, s.turn) {
+		return sendVideoFrame(s.p, s.c, s.world, s.turn)
+	}
+	return nil
+}
+
+// send reports an event, giving up if the run is stopped while the receiver is not listening.
+func (c distributorChannels) send(event Event) error {
+	select {
+	case c.events <- event:
+		return nil
+	case <-c.done:
+		return errStopped
+	}
+}
+
+// io sends a command to the io goroutine.
+func (c distributorChannels) io(command ioCommand) error {
+	select {
+	case c.ioCommand <- command:
+		return nil
+	case <-c.done:
+		return errStopped
+	}
+}
+
+// sendWorld sends the world to the io goroutine row by row, followed by a nil row.
+// The rows are not copied, but io only receives the nil row once it has finished with them,
+// so the world can be changed again as soon as sendWorld returns.
+func (c distributorChannels) sendWorld(world [][]byte) error {
+	for _, row := range world {
+		select {
+		case c.ioOutput <- row:
+		case <-c.done:
+			return errStopped
+		}
+	}
+	select {
+	case c.ioOutput <- nil:
+		return nil
+	case <-c.done:
+		return errStopped
+	}
+}
+
+// sendSnapshot tells the io goroutine where the world it is about to receive should be written.
+func (c distributorChannels) sendSnapshot(snapshot ImageOutputComplete) error {
+	select {
+	case c.ioSnapshot <- snapshot:
+		return nil
+	case <-c.done:
+		return errStopped
+	}
+}
+
+// loadWorld asks the io goroutine for the input image and builds the initial world from it.
+func loadWorld(p Params, c distributorChannels) ([][]byte, error) {
+	filename := inputFilename(p)
+	command := ioImport
+	switch {
+	case isPgmFile(filename):
+		command = ioInput
+	case isCheckpointFile(filename):
+		command = ioResume
+	case isMacrocellFile(filename):
+		command = ioMacrocell
+	case isRLEFile(filename):
+		command = ioRLE
+	}
+	if err := c.io(command); err != nil {
+		return nil, err
+	}
+	select {
+	case c.ioFilename <- filename:
+	case <-c.done:
+		return nil, errStopped
+	}
+
+	world := make([][]byte, p.ImageHeight)
+	for y := range world {
+		select {
+		case world[y] = <-c.ioInput:
+		case <-c.done:
+			return nil, errStopped
+		}
+	}
+	return world, nil
+}
+
+// inputFilename is the path of the image that the initial world is loaded from.
+func inputFilename(p Params) string {
+	switch {
+	case p.ResumeFile != "":
+		return p.ResumeFile
+	case p.InputFile != "":
+		return p.InputFile
+	case p.ImportFile != "":
+		return p.ImportFile
+	default:
+		return filepath.Join("images", fmt.Sprintf("%vx%v.pgm", p.ImageWidth, p.ImageHeight))
+	}
+}
+
+// saveWorld hands the world to the io goroutine to be written as a pgm image in the background.
+// The io goroutine sends ImageOutputComplete once the file has been written.
+func saveWorld(p Params, c distributorChannels, world [][]byte, turn int) error {
+	return writeImage(p, c, world, turn, outputFilename(p, turn))
+}
+
+// saveFrame writes the world as the next frame of an image sequence.
+// Frames and snapshots both go through the io goroutine in the order they were requested, so they never race.
+func saveFrame(p Params, c distributorChannels, world [][]byte, turn int) error {
+	return writeImage(p, c, world, turn, frameFilename(p, turn))
+}
+
+// writeImage hands the world to the io goroutine to be written as the pgm image called filename.
+func writeImage(p Params, c distributorChannels, world [][]byte, turn int, filename string) error {
+	if err := c.io(ioOutput); err != nil {
+		return err
+	}
+	if err := c.sendSnapshot(ImageOutputComplete{turn, filename, outputPath(p, filename)}); err != nil {
+		return err
+	}
+	return c.sendWorld(world)
+}
+
+// saveCheckpoint hands the world to the io goroutine to be written as a checkpoint that can be resumed from.
+func saveCheckpoint(p Params, c distributorChannels, world [][]byte, turn int, filename string) error {
+	if err := c.io(ioCheckpoint); err != nil {
+		return err
+	}
+	if err := c.sendSnapshot(ImageOutputComplete{turn, filename, checkpointPath(p, filename)}); err != nil {
+		return err
+	}
+	return c.sendWorld(world)
+}
+
+// isVideoTurn reports whether the world after turn should be added to the video.
+func isVideoTurn(p Params, turn int) bool {
+	return p.VideoFile != "" && (p.VideoEvery <= 1 || turn%p.VideoEvery == 0)
+}
+
+// sendVideoFrame hands the world to the io goroutine to be appended to the video stream.
+// The turn is only used to report a failed write.
+func sendVideoFrame(p Params, c distributorChannels, world [][]byte, turn int) error {
+	if err := c.io(ioVideoFrame); err != nil {
+		return err
+	}
+	if err := c.sendSnapshot(ImageOutputComplete{turn, p.VideoFile, p.VideoFile}); err != nil {
+		return err
+	}
+	return c.sendWorld(world)
+}
+
+// calculateNextTurn splits the world into horizontal strips and evolves each strip on its own worker.
+func calculateNextTurn(p Params, world [][]byte) [][]byte {
+	threads := p.Threads
+	if threads < 1 {
+		threads = 1
+	}
+	if threads > p.ImageHeight {
+		threads = p.ImageHeight
+	}
+
+	strips := make([]chan [][]byte, threads)
+	for i := range strips {
+		startY := i * p.ImageHeight / threads
+		endY := (i + 1) * p.ImageHeight / threads
+		strips[i] = make(chan [][]byte)
+		go worker(p, world, startY, endY, strips[i])
+	}
+
+	next := make([][]byte, 0, p.ImageHeight)
+	for _, strip := range strips {
+		next = append(next, <-strip...)
+	}
+	return next
+}
+
+// diffWorlds returns every cell whose state differs between the two worlds.
+func diffWorlds(p Params, before, after [][]byte) []util.Cell {
+	var flipped []util.Cell
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if before[y][x] != after[y][x] {
+				flipped = append(flipped, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return flipped
+}
+
+// calculateAliveCells returns the coordinates of every alive cell in the world.
+func calculateAliveCells(p Params, world [][]byte) []util.Cell {
+	var alive []util.Cell
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if world[y][x] == aliveCell {
+				alive = append(alive, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return alive
+}
 
-	c.events <- StateChange{turn, Quitting}
-	
-	// Close the channel to stop the SDL goroutine gracefully. Removing may cause deadlock.
-	close(c.events)
+func makeWorld(height, width int) [][]byte {
+	world := make([][]byte, height)
+	for i := range world {
+		world[i] = make([]byte, width)
+	}
+	return world
 }
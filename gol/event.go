@@ -23,9 +23,12 @@ type AliveCellsCount struct { // implements Event
 
 // ImageOutputComplete is an Event notifying the user about the completion of output.
 // This Event should be sent every time an image has been saved.
+// Path is where the image was written, including the output directory and extension.
+// It is also sent for each checkpoint written, with a .ckpt Path.
 type ImageOutputComplete struct { // implements Event
 	CompletedTurns int
 	Filename       string
+	Path           string
 }
 
 // State represents a change in the state of execution.
@@ -35,13 +38,19 @@ const (
 	Paused State = iota
 	Executing
 	Quitting
+	// Stepping is executing a fixed number of turns while paused, after which the state returns to Paused.
+	Stepping
+	// Throttled is executing with a limit on the number of turns per second.
+	Throttled
 )
 
 // StateChange is an Event notifying the user about the change of state of execution.
-// This Event should be sent every time the execution is paused, resumed or quit.
+// This Event should be sent every time the execution is paused, resumed, stepped, throttled or quit.
+// TurnsPerSecond is the speed limit set with SetSpeed, or zero if there is none.
 type StateChange struct { // implements Event
 	CompletedTurns int
 	NewState       State
+	TurnsPerSecond float64
 }
 
 // CellFlipped is an Event notifying the GUI about a change of state of a single cell.
@@ -52,13 +61,43 @@ type CellFlipped struct { // implements Event
 	Cell           util.Cell
 }
 
+// CellsFlipped is an Event carrying every cell that changed state in one turn, sent instead of
+// CellFlipped events when Params.BatchFlips is set. It is also used for the cells that are alive when the image is loaded in.
+// With Params.FrameRate set it carries the cells that changed since the last frame, over any number of turns.
+// Cells changed by Edit or Undo are always sent in a CellsFlipped event of their own.
+type CellsFlipped struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+	// Turns is how many turns the cells changed over: 1 for a single turn, more when FrameRate skipped turns,
+	// and 0 when they were changed by loading the world, Edit or Undo rather than by turns.
+	Turns int
+}
+
 // TurnComplete is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
-// All CellFlipped events must be sent *before* TurnComplete.
+// All CellFlipped and CellsFlipped events must be sent *before* TurnComplete.
+// With Params.FrameRate set, it is only sent for the turns that are shown, always including the last one.
+// It is sent again for the same turn after an Edit or Undo.
 type TurnComplete struct { // implements Event
 	CompletedTurns int
 }
 
+// Statistics is how the population changed in one turn.
+type Statistics struct {
+	Turn  int
+	Alive int
+	Born  int
+	Died  int
+}
+
+// TurnStatistics is an Event carrying the Statistics of every turn since the last TurnStatistics, oldest first.
+// It is only sent if Params.ReportStatistics is set, before the TurnComplete of the last of them.
+// With Params.FrameRate set it is sent once per frame.
+type TurnStatistics struct { // implements Event
+	CompletedTurns int
+	Turns          []Statistics
+}
+
 // FinalTurnComplete is an Event notifying the testing framework about the new world state after execution finished.
 // The data included with this Event is used directly by the tests.
 // SDL closes the window when this Event is sent.
@@ -67,6 +106,14 @@ type FinalTurnComplete struct {
 	Alive          []util.Cell
 }
 
+// Error is an Event notifying the user that reading or writing a file failed.
+// Failed snapshots, frames, checkpoints and videos are reported as they happen and the run carries on.
+// If the world cannot be loaded, Error is the last Event before the events channel is closed.
+type Error struct { // implements Event
+	CompletedTurns int
+	Err            error
+}
+
 // String methods allow the different types of Events and States to be printed.
 
 func (state State) String() string {
@@ -77,12 +124,19 @@ func (state State) String() string {
 		return "Executing"
 	case Quitting:
 		return "Quitting"
+	case Stepping:
+		return "Stepping"
+	case Throttled:
+		return "Throttled"
 	default:
 		return "Incorrect State"
 	}
 }
 
 func (event StateChange) String() string {
+	if event.TurnsPerSecond > 0 {
+		return fmt.Sprintf("%v (%v turns per second)", event.NewState, event.TurnsPerSecond)
+	}
 	return fmt.Sprintf("%v", event.NewState)
 }
 
@@ -99,7 +153,7 @@ func (event AliveCellsCount) GetCompletedTurns() int {
 }
 
 func (event ImageOutputComplete) String() string {
-	return fmt.Sprintf("File %v output complete", event.Filename)
+	return fmt.Sprintf("File %v output complete", event.Path)
 }
 
 func (event ImageOutputComplete) GetCompletedTurns() int {
@@ -114,6 +168,14 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event CellsFlipped) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellsFlipped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -122,6 +184,14 @@ func (event TurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event TurnStatistics) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event TurnStatistics) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event FinalTurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -130,6 +200,14 @@ func (event FinalTurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event Error) String() string {
+	return fmt.Sprintf("Error: %v", event.Err)
+}
+
+func (event Error) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 // This might all seem like weird syntax to you...
 // You have however seen something similar to it before in first year.
 
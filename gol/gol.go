@@ -1,38 +1,182 @@
 package gol
 
+import (
+	"context"
+	"errors"
+	"io"
+)
+
 // Params provides the details of how to run the Game of Life and which image to load.
 type Params struct {
 	Turns       int
 	Threads     int
 	ImageWidth  int
 	ImageHeight int
+
+	// InputFile is a pgm, png, jpeg, gif, macrocell or RLE file to open at its own size instead of images/<W>x<H>.pgm.
+	// When set, ImageWidth and ImageHeight are replaced by the dimensions in the file header.
+	InputFile string
+
+	// ImportFile is a png, jpeg or gif image to seed the world from instead of images/<W>x<H>.pgm.
+	// It is scaled to ImageWidth x ImageHeight.
+	ImportFile string
+	// ImportThreshold is the brightness (1-255) at which an imported pixel becomes alive. Zero means 128.
+	ImportThreshold uint8
+	// ImportDither converts imported images with Floyd–Steinberg dithering instead of a plain threshold.
+	ImportDither bool
+
+	// OutputDir is the directory snapshots are written to. Defaults to "out".
+	OutputDir string
+	// OutputTemplate names each snapshot. {w}, {h}, {turn}, {threads} and {rule} are replaced,
+	// {turn:N} is the turn zero-padded to N digits and '/' creates subdirectories. Defaults to "{w}x{h}x{turn}".
+	OutputTemplate string
+	// FrameEvery writes a numbered frame every FrameEvery turns, starting with the initial world,
+	// for assembling into an animation. Zero disables frames.
+	FrameEvery int
+	// FrameTemplate names each frame like OutputTemplate. Defaults to "{w}x{h}x{turn:4}".
+	FrameTemplate string
+
+	// VideoFile streams the run as a greyscale YUV4MPEG2 video to this file, or to stdout if it is "-".
+	VideoFile string
+	// VideoEvery adds a video frame every VideoEvery turns, starting with the initial world. Zero means every turn.
+	VideoEvery int
+	// VideoScale draws each cell as a VideoScale x VideoScale block. Zero means 1.
+	VideoScale int
+	// VideoOutput is where a VideoFile of "-" is streamed. Nil means os.Stdout.
+	VideoOutput io.Writer
+	// NoClobber refuses to overwrite snapshots, frames and videos that already exist.
+	NoClobber bool
+	// Log receives a line for every file read or written. Nil means os.Stdout.
+	Log io.Writer
+
+	// CheckpointEvery writes a checkpoint every CheckpointEvery turns. Zero disables periodic checkpoints.
+	// Checkpoints are named like snapshots but with a .ckpt extension.
+	CheckpointEvery int
+	// ResumeFile is a checkpoint to continue from. The world size and completed turns are taken from it,
+	// and Turns is still the total number of turns to reach.
+	ResumeFile string
+
+	// BatchFlips sends the cells that change in each turn as one CellsFlipped event
+	// instead of a CellFlipped event per cell, which is much faster for busy worlds.
+	BatchFlips bool
+	// FrameRate limits the frames sent to a visualiser to about FrameRate per second. Turns run at full speed,
+	// and the cells that changed since the last frame are sent as one CellsFlipped event followed by TurnComplete.
+	// No frame is sent while the events of the previous one are still waiting to be read. Zero sends every turn.
+	FrameRate float64
+	// ReportStatistics sends the population, births and deaths of every turn in TurnStatistics events.
+	ReportStatistics bool
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
+// keyPresses accepts the SDL window's keys: 'p' pauses and resumes, 'n' steps one turn while paused,
+// '-' and '+' slow down and speed up, 'u' undoes an edit, 's' saves a snapshot, 'c' saves a checkpoint, and 'q' and 'k' quit.
+// Files that cannot be read or written are reported as Error events.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	var commands chan Command
+	if keyPresses != nil {
+		commands = make(chan Command)
+		done := make(chan struct{})
+		defer close(done)
+		go keyCommands(keyPresses, commands, done)
+	}
+	RunCommands(p, events, commands)
+}
+
+// RunCommands is like Run but is controlled by typed Commands, which can carry arguments and be acknowledged.
+// If the world cannot be loaded, the reason is sent as an Error event before events is closed.
+func RunCommands(p Params, events chan<- Event, commands <-chan Command) {
+	_ = RunContext(context.Background(), p, events, commands)
+}
+
+// errStopped is returned internally by the distributor and io goroutine when they are told to give up waiting.
+var errStopped = errors.New("run stopped")
+
+// RunContext is like RunCommands but can be cancelled through ctx, and also returns the error that stopped the run.
+// It always closes events before returning. The error wraps the underlying cause, such as a missing input file,
+// or is ctx.Err() if the run was cancelled first. Failed writes are only reported as Error events.
+func RunContext(ctx context.Context, p Params, events chan<- Event, commands <-chan Command) error {
+	turn, err := run(ctx, p, events, commands)
+	if err != nil && ctx.Err() == nil {
+		// Tell whoever is reading events why the run stopped.
+		select {
+		case events <- Error{turn, err}:
+		case <-ctx.Done():
+		}
+	}
+	close(events)
+	return err
+}
+
+// run loads the world and executes it, returning the turn it started from and the error that stopped it.
+// Errors only happen while loading the world, so they are reported at the starting turn.
+func run(ctx context.Context, p Params, events chan<- Event, commands <-chan Command) (int, error) {
+	if p.InputFile != "" {
+		width, height, err := ImageDimensions(p.InputFile)
+		if err != nil {
+			return 0, err
+		}
+		p.ImageWidth, p.ImageHeight = width, height
+	}
+	turn := 0
+	if p.ResumeFile != "" {
+		completed, width, height, err := CheckpointInfo(p.ResumeFile)
+		if err != nil {
+			return 0, err
+		}
+		turn = completed
+		p.ImageWidth, p.ImageHeight = width, height
+	}
+	if err := checkOutputTemplate(p); err != nil {
+		return turn, err
+	}
+
+	running, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	ioCommand := make(chan ioCommand)
 	ioIdle := make(chan bool)
 	ioFilename := make(chan string)
-	ioOutput := make(chan uint8)
-	ioInput := make(chan uint8)
+	ioSnapshot := make(chan ImageOutputComplete)
+	ioOutput := make(chan []byte)
+	ioInput := make(chan []byte)
 
 	ioChannels := ioChannels{
 		command:  ioCommand,
 		idle:     ioIdle,
+		events:   events,
 		filename: ioFilename,
+		snapshot: ioSnapshot,
 		output:   ioOutput,
 		input:    ioInput,
+		done:     running.Done(),
 	}
-	go startIo(p, ioChannels)
+	ioError := make(chan error, 1)
+	go func() {
+		// An io failure cancels the run, so the distributor stops waiting for it.
+		ioError <- startIo(p, ioChannels)
+		cancel()
+	}()
 
 	distributorChannels := distributorChannels{
 		events:     events,
 		ioCommand:  ioCommand,
 		ioIdle:     ioIdle,
 		ioFilename: ioFilename,
+		ioSnapshot: ioSnapshot,
 		ioOutput:   ioOutput,
 		ioInput:    ioInput,
+		commands:   commands,
+		done:       running.Done(),
+	}
+	err := distributor(p, distributorChannels, turn)
+
+	// Wait for the io goroutine to stop so it cannot send on events after it is closed.
+	cancel()
+	if err := <-ioError; err != nil {
+		return turn, err
+	}
+	if err != nil && ctx.Err() != nil {
+		return turn, ctx.Err()
 	}
-	distributor(p, distributorChannels)
+	return turn, err
 }
@@ -0,0 +1,264 @@
+package gol
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runContext runs RunContext in the background and returns its error once it finishes.
+// It fails the test if the run takes longer than five seconds.
+func runContext(t *testing.T, ctx context.Context, p Params, events chan Event) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- RunContext(ctx, p, events, nil)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return")
+		return nil
+	}
+}
+
+// TestRunContextMissingInput checks that a missing input file is returned and reported rather than causing a panic.
+func TestRunContextMissingInput(t *testing.T) {
+	p := Params{Turns: 1, Threads: 1, InputFile: filepath.Join(t.TempDir(), "missing.pgm")}
+	events := make(chan Event, 1000)
+
+	err := runContext(t, context.Background(), p, events)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+	if event, ok := (<-events).(Error); !ok || event.Err != err {
+		t.Errorf("expected the error to be reported, got %v", event)
+	}
+	if _, open := <-events; open {
+		t.Error("events was not closed")
+	}
+}
+
+// TestRunContextWriteFailure checks that failed snapshots are reported as Error events and the run carries on.
+func TestRunContextWriteFailure(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(outputDir, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	p := Params{
+		Turns:      3,
+		Threads:    2,
+		InputFile:  filepath.Join("..", "images", "16x16.pgm"),
+		OutputDir:  outputDir,
+		FrameEvery: 1,
+	}
+	events := make(chan Event, 1000)
+
+	if err := runContext(t, context.Background(), p, events); err != nil {
+		t.Fatal(err)
+	}
+	errorTurns := make(map[int]bool)
+	final := false
+	for event := range events {
+		switch e := event.(type) {
+		case Error:
+			if !strings.Contains(e.Err.Error(), outputDir) {
+				t.Errorf("expected an error writing to %v, got %v", outputDir, e.Err)
+			}
+			errorTurns[e.CompletedTurns] = true
+		case ImageOutputComplete:
+			t.Errorf("unexpected %v", e)
+		case FinalTurnComplete:
+			final = true
+		}
+	}
+	// Frames for turns 0 to 3 and the final snapshot at turn 3.
+	if len(errorTurns) != 4 {
+		t.Errorf("expected errors for turns 0 to 3, got %v", errorTurns)
+	}
+	if !final {
+		t.Error("run did not finish")
+	}
+}
+
+// TestRunContextCancel checks that cancelling stops a run whose events are no longer being read.
+func TestRunContextCancel(t *testing.T) {
+	p := Params{
+		Turns:     1 << 30,
+		Threads:   4,
+		InputFile: filepath.Join("..", "images", "64x64.pgm"),
+		OutputDir: t.TempDir(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event)
+	result := make(chan error, 1)
+	go func() {
+		result <- RunContext(ctx, p, events, nil)
+	}()
+
+	for event := range events {
+		if _, ok := event.(TurnComplete); ok {
+			break
+		}
+	}
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return after being cancelled")
+	}
+	if _, open := <-events; open {
+		t.Error("events was not closed")
+	}
+}
+
+// TestBatchFlips checks that applying every CellsFlipped event reproduces the final world.
+func TestBatchFlips(t *testing.T) {
+	p := Params{
+		Turns:      20,
+		Threads:    4,
+		InputFile:  filepath.Join("..", "images", "64x64.pgm"),
+		OutputDir:  t.TempDir(),
+		BatchFlips: true,
+	}
+	events := make(chan Event, 1000)
+	go Run(p, events, nil)
+
+	board := makeWorld(64, 64)
+	var final FinalTurnComplete
+	for event := range events {
+		switch e := event.(type) {
+		case CellFlipped:
+			t.Fatalf("unexpected CellFlipped %v", e)
+		case CellsFlipped:
+			for _, cell := range e.Cells {
+				board[cell.Y][cell.X] = ^board[cell.Y][cell.X]
+			}
+		case FinalTurnComplete:
+			final = e
+		}
+	}
+	if alive := calculateAliveCells(Params{ImageWidth: 64, ImageHeight: 64}, board); !reflect.DeepEqual(alive, final.Alive) {
+		t.Errorf("flipped cells give %v alive cells, expected %v", len(alive), len(final.Alive))
+	}
+}
+
+// benchmarkFlips runs a 512x512 soup for 10 turns and reads every event.
+func benchmarkFlips(b *testing.B, batch bool) {
+	p := Params{
+		Turns:      10,
+		Threads:    4,
+		InputFile:  filepath.Join("..", "images", "512x512.pgm"),
+		OutputDir:  b.TempDir(),
+		BatchFlips: batch,
+	}
+	for i := 0; i < b.N; i++ {
+		events := make(chan Event, 1000)
+		go Run(p, events, nil)
+		for range events {
+		}
+	}
+}
+
+// BenchmarkCellFlipped sends one event per changed cell.
+func BenchmarkCellFlipped(b *testing.B) {
+	benchmarkFlips(b, false)
+}
+
+// BenchmarkCellsFlipped sends one event per turn.
+func BenchmarkCellsFlipped(b *testing.B) {
+	benchmarkFlips(b, true)
+}
+
+// TestFrameRate checks that a limited frame rate skips turns, says how many each frame covers,
+// and still shows the final world.
+func TestFrameRate(t *testing.T) {
+	p := Params{
+		Turns:     1000,
+		Threads:   4,
+		InputFile: filepath.Join("..", "images", "64x64.pgm"),
+		OutputDir: t.TempDir(),
+		FrameRate: 20,
+	}
+	events := make(chan Event, 1000)
+	go Run(p, events, nil)
+
+	board := makeWorld(64, 64)
+	frames, lastFrame := 0, -1
+	var final FinalTurnComplete
+	for event := range events {
+		switch e := event.(type) {
+		case CellsFlipped:
+			for _, cell := range e.Cells {
+				board[cell.Y][cell.X] = ^board[cell.Y][cell.X]
+			}
+			// The flips of the loaded world cover no turns.
+			if lastFrame >= 0 && e.Turns != e.CompletedTurns-lastFrame {
+				t.Errorf("expected the frame at turn %v to cover %v turns, got %v", e.CompletedTurns, e.CompletedTurns-lastFrame, e.Turns)
+			}
+		case TurnComplete:
+			frames++
+			lastFrame = e.CompletedTurns
+		case FinalTurnComplete:
+			final = e
+		}
+	}
+	if frames >= p.Turns/2 {
+		t.Errorf("expected turns to be skipped, got %v frames for %v turns", frames, p.Turns)
+	}
+	if lastFrame != p.Turns {
+		t.Errorf("expected the last frame to show turn %v, got %v", p.Turns, lastFrame)
+	}
+	if alive := calculateAliveCells(Params{ImageWidth: 64, ImageHeight: 64}, board); !reflect.DeepEqual(alive, final.Alive) {
+		t.Errorf("frames give %v alive cells, expected %v", len(alive), len(final.Alive))
+	}
+}
+
+// TestStatistics checks that every turn is reported once, with or without FrameRate,
+// and that the statistics agree with stepping a Simulation.
+func TestStatistics(t *testing.T) {
+	for _, frameRate := range []float64{0, 20} {
+		p := Params{
+			Turns:            200,
+			Threads:          4,
+			InputFile:        filepath.Join("..", "images", "64x64.pgm"),
+			OutputDir:        t.TempDir(),
+			FrameRate:        frameRate,
+			ReportStatistics: true,
+		}
+		events := make(chan Event, 1000)
+		go Run(p, events, nil)
+		var stats []Statistics
+		for event := range events {
+			if e, ok := event.(TurnStatistics); ok {
+				stats = append(stats, e.Turns...)
+			}
+		}
+		if len(stats) != p.Turns {
+			t.Fatalf("frame rate %v: expected statistics for %v turns, got %v", frameRate, p.Turns, len(stats))
+		}
+
+		s, err := LoadSimulation(p.InputFile, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, got := range stats {
+			before := s.World()
+			s.Step(1)
+			born, died := countChanges(before, s.world)
+			if want := (Statistics{s.Turn(), s.Population(), born, died}); got != want {
+				t.Fatalf("frame rate %v: expected %+v, got %+v", frameRate, want, got)
+			}
+		}
+	}
+}
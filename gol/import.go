@@ -0,0 +1,147 @@
+package gol
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// defaultImportThreshold is used when Params.ImportThreshold is left at zero.
+const defaultImportThreshold = 128
+
+// importImage decodes a png, jpeg or gif image, scales it to the size of the world
+// and sends it to the distributor as alive and dead cells.
+func (io *ioState) importImage() error {
+
+	// Request a filename from the distributor.
+	filename, err := io.receiveFilename()
+	if err != nil {
+		return err
+	}
+
+	file, ioError := os.Open(filename)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
+	defer file.Close()
+
+	img, _, ioError := image.Decode(file)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
+
+	brightness := scaleToGrey(img, io.params.ImageWidth, io.params.ImageHeight)
+
+	threshold := float64(io.params.ImportThreshold)
+	if threshold == 0 {
+		threshold = defaultImportThreshold
+	}
+
+	var world [][]byte
+	if io.params.ImportDither {
+		world = ditherCells(brightness, threshold)
+	} else {
+		world = thresholdCells(brightness, threshold)
+	}
+
+	if err := io.sendWorld(world); err != nil {
+		return err
+	}
+
+	io.log("File", filename, "import done!")
+	return nil
+}
+
+// scaleToGrey resizes img to width x height and returns the brightness (0-255) of every pixel.
+// Each output pixel is the average of the source pixels it covers, or the nearest source pixel when enlarging.
+func scaleToGrey(img image.Image, width, height int) [][]float64 {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	grey := make([][]float64, height)
+	for y := range grey {
+		grey[y] = make([]float64, width)
+
+		startY := y * srcHeight / height
+		endY := (y + 1) * srcHeight / height
+		if endY <= startY {
+			endY = startY + 1
+		}
+
+		for x := range grey[y] {
+			startX := x * srcWidth / width
+			endX := (x + 1) * srcWidth / width
+			if endX <= startX {
+				endX = startX + 1
+			}
+
+			sum := 0.0
+			for sy := startY; sy < endY; sy++ {
+				for sx := startX; sx < endX; sx++ {
+					c := color.GrayModel.Convert(img.At(bounds.Min.X+sx, bounds.Min.Y+sy)).(color.Gray)
+					sum += float64(c.Y)
+				}
+			}
+			grey[y][x] = sum / float64((endY-startY)*(endX-startX))
+		}
+	}
+	return grey
+}
+
+// thresholdCells makes every pixel at least as bright as threshold alive.
+func thresholdCells(brightness [][]float64, threshold float64) [][]byte {
+	world := make([][]byte, len(brightness))
+	for y, row := range brightness {
+		world[y] = make([]byte, len(row))
+		for x, b := range row {
+			if b >= threshold {
+				world[y][x] = aliveCell
+			}
+		}
+	}
+	return world
+}
+
+// ditherCells converts brightness to alive and dead cells using Floyd–Steinberg dithering,
+// so that the density of alive cells follows the brightness of the image.
+func ditherCells(brightness [][]float64, threshold float64) [][]byte {
+	height := len(brightness)
+	levels := make([][]float64, height)
+	for y := range levels {
+		levels[y] = make([]float64, len(brightness[y]))
+		copy(levels[y], brightness[y])
+	}
+
+	world := make([][]byte, height)
+	for y := range world {
+		width := len(levels[y])
+		world[y] = make([]byte, width)
+		for x := 0; x < width; x++ {
+			old := levels[y][x]
+			value := 0.0
+			if old >= threshold {
+				world[y][x] = aliveCell
+				value = 255
+			}
+			diff := old - value
+
+			if x+1 < width {
+				levels[y][x+1] += diff * 7 / 16
+			}
+			if y+1 < height {
+				if x > 0 {
+					levels[y+1][x-1] += diff * 3 / 16
+				}
+				levels[y+1][x] += diff * 5 / 16
+				if x+1 < width {
+					levels[y+1][x+1] += diff * 1 / 16
+				}
+			}
+		}
+	}
+	return world
+}
@@ -0,0 +1,68 @@
+package gol
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// uniformImage returns a width x height image filled with a single grey level.
+func uniformImage(width, height int, level uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = level
+	}
+	return img
+}
+
+func countAlive(world [][]byte) int {
+	count := 0
+	for _, row := range world {
+		for _, b := range row {
+			if b == aliveCell {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// TestScaleToGrey checks that downscaling averages the covered pixels and upscaling repeats them.
+func TestScaleToGrey(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	img.SetGray(0, 0, color.Gray{Y: 255})
+	img.SetGray(1, 1, color.Gray{Y: 255})
+
+	down := scaleToGrey(img, 2, 1)
+	if down[0][0] != 127.5 || down[0][1] != 0 {
+		t.Errorf("expected [127.5 0] when downscaling, got %v", down[0])
+	}
+
+	up := scaleToGrey(img, 8, 4)
+	if up[0][0] != 255 || up[0][1] != 255 || up[1][0] != 255 || up[0][2] != 0 {
+		t.Errorf("expected pixel (0, 0) to cover a 2x2 block when upscaling, got %v", up[:2])
+	}
+}
+
+// TestThresholdCells checks that pixels are alive exactly when they reach the threshold.
+func TestThresholdCells(t *testing.T) {
+	world := thresholdCells([][]float64{{0, 127, 128, 255}}, 128)
+	expected := []byte{deadCell, deadCell, aliveCell, aliveCell}
+	for x, b := range expected {
+		if world[0][x] != b {
+			t.Errorf("cell %d: expected %v, got %v", x, b, world[0][x])
+		}
+	}
+}
+
+// TestDitherCells checks that dithering a flat grey image keeps the same proportion of alive cells.
+func TestDitherCells(t *testing.T) {
+	for _, level := range []uint8{0, 64, 128, 192, 255} {
+		brightness := scaleToGrey(uniformImage(64, 64, level), 64, 64)
+		alive := countAlive(ditherCells(brightness, defaultImportThreshold))
+		expected := 64 * 64 * int(level) / 255
+		if diff := alive - expected; diff < -64 || diff > 64 {
+			t.Errorf("level %d: expected about %d alive cells, got %d", level, expected, alive)
+		}
+	}
+}
@@ -1,29 +1,59 @@
 package gol
 
 import (
+	"bufio"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"strconv"
-	"strings"
-	"uk.ac.bris.cs/gameoflife/util"
+	"path/filepath"
+	"sync"
 )
 
 type ioChannels struct {
 	command <-chan ioCommand
 	idle    chan<- bool
+	events  chan<- Event
 
 	filename <-chan string
-	output   <-chan uint8
-	input    chan<- uint8
+	snapshot <-chan ImageOutputComplete
+	output   <-chan []byte
+	input    chan<- []byte
+
+	// done is closed when the run stops, so that the io goroutine gives up waiting for the distributor.
+	done <-chan struct{}
 }
 
 // ioState is the internal ioState of the io goroutine.
 type ioState struct {
 	params   Params
 	channels ioChannels
+
+	// Snapshots are copied into one of two buffers and written to disk by a background goroutine,
+	// so the distributor only waits for the copy. free holds the buffers that are not in use.
+	pending chan pendingSnapshot
+	free    chan [][]byte
+	writing sync.WaitGroup
+	// stopped is closed once the io goroutine exits.
+	stopped chan struct{}
+
+	// video is the open Y4M stream, created when the first frame arrives.
+	// videoTurn is the turn of the last frame, and videoFailed stops the stream after a failed write.
+	video       *y4mWriter
+	videoFile   *os.File
+	videoTurn   int
+	videoFailed bool
+}
+
+// pendingSnapshot is a copy of the world waiting to be written to event.Path,
+// either as a pgm image or as a checkpoint.
+type pendingSnapshot struct {
+	event      ImageOutputComplete
+	world      [][]byte
+	checkpoint bool
 }
 
+// syncFile flushes a written snapshot to disk. Tests replace it to simulate a slow disk.
+var syncFile = (*os.File).Sync
+
 // ioCommand allows requesting behaviour from the io (pgm) goroutine.
 type ioCommand uint8
 
@@ -32,118 +62,498 @@ type ioCommand uint8
 //		ioOutput 	= 0
 //		ioInput 	= 1
 //		ioCheckIdle = 2
+//		ioImport 	= 3
+//		ioCheckpoint = 4
+//		ioResume 	= 5
+//		ioMacrocell = 6
+//		ioVideoFrame = 7
+//		ioVideoClose = 8
+//		ioRLE 		= 9
 const (
 	ioOutput ioCommand = iota
 	ioInput
 	ioCheckIdle
+	ioImport
+	ioCheckpoint
+	ioResume
+	ioMacrocell
+	ioVideoFrame
+	ioVideoClose
+	ioRLE
 )
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
-func (io *ioState) writePgmImage() {
-	_ = os.Mkdir("out", os.ModePerm)
+// receiveFilename waits for the distributor to send the name of the file to read.
+func (io *ioState) receiveFilename() (string, error) {
+	select {
+	case filename := <-io.channels.filename:
+		return filename, nil
+	case <-io.channels.done:
+		return "", errStopped
+	}
+}
+
+// receiveRow waits for the distributor to send the next row of the world.
+func (io *ioState) receiveRow() ([]byte, error) {
+	select {
+	case row := <-io.channels.output:
+		return row, nil
+	case <-io.channels.done:
+		return nil, errStopped
+	}
+}
 
-	// Request a filename from the distributor.
-	filename := <-io.channels.filename
+// receiveEnd waits for the nil row the distributor sends after a world, which lets it change the world again.
+func (io *ioState) receiveEnd() error {
+	_, err := io.receiveRow()
+	return err
+}
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	util.Check(ioError)
-	defer file.Close()
+// sendWorld sends a world that has been read to the distributor row by row.
+func (io *ioState) sendWorld(world [][]byte) error {
+	for _, row := range world {
+		if err := io.sendRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendRow sends the next row of the world that is being read to the distributor.
+func (io *ioState) sendRow(row []byte) error {
+	select {
+	case io.channels.input <- row:
+		return nil
+	case <-io.channels.done:
+		return errStopped
+	}
+}
 
-	_, _ = file.WriteString("P5\n")
-	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
-	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
-	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
-	_, _ = file.WriteString("\n")
+// writePgmImage receives the world row by row and queues a copy of it for the background writer.
+// It returns as soon as the copy is made. ioCheckIdle waits for the file to be written.
+func (io *ioState) writePgmImage() error {
+	return io.queueSnapshot(false)
+}
+
+// saveCheckpoint is like writePgmImage but writes the world in the checkpoint format.
+func (io *ioState) saveCheckpoint() error {
+	return io.queueSnapshot(true)
+}
 
-	world := make([][]byte, io.params.ImageHeight)
-	for i := range world {
-		world[i] = make([]byte, io.params.ImageWidth)
+// queueSnapshot copies the world sent by the distributor and queues it for the background writer.
+func (io *ioState) queueSnapshot(checkpoint bool) error {
+	// Request the snapshot details from the distributor.
+	var snapshot ImageOutputComplete
+	select {
+	case snapshot = <-io.channels.snapshot:
+	case <-io.channels.done:
+		return errStopped
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			val := <-io.channels.output
-			//if val != 0 {
-			//	fmt.Println(x, y)
-			//}
-			world[y][x] = val
+	// Blocks while both buffers are busy, so a slow disk holds the distributor back rather than using unbounded memory.
+	var world [][]byte
+	select {
+	case world = <-io.free:
+	case <-io.channels.done:
+		return errStopped
+	}
+	if world == nil {
+		world = makeWorld(io.params.ImageHeight, io.params.ImageWidth)
+	}
+	for y := range world {
+		row, err := io.receiveRow()
+		if err != nil {
+			io.free <- world
+			return err
 		}
+		copy(world[y], row)
+	}
+	if err := io.receiveEnd(); err != nil {
+		io.free <- world
+		return err
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			util.Check(ioError)
+	io.writing.Add(1)
+	io.pending <- pendingSnapshot{snapshot, world, checkpoint}
+	return nil
+}
+
+// writeSnapshots writes queued snapshots to disk and reports each one with an ImageOutputComplete event.
+// A snapshot that cannot be written is reported with an Error event instead, and the run carries on.
+func (io *ioState) writeSnapshots() {
+	for snapshot := range io.pending {
+		var err error
+		if snapshot.checkpoint {
+			err = io.writeCheckpointFile(snapshot.event.Path, snapshot.event.CompletedTurns, snapshot.world)
+		} else {
+			err = io.writePgmFile(snapshot.event.Path, snapshot.world)
 		}
+		io.free <- snapshot.world
+		if err != nil {
+			io.report(Error{snapshot.event.CompletedTurns, err})
+		} else {
+			io.report(snapshot.event)
+		}
+		io.writing.Done()
 	}
+}
 
-	ioError = file.Sync()
-	util.Check(ioError)
+// report sends an event from the io goroutine, unless the run has stopped.
+func (io *ioState) report(event Event) {
+	select {
+	case io.channels.events <- event:
+	case <-io.channels.done:
+	case <-io.stopped:
+	}
+}
+
+// log prints a line about a file that was read or written to Params.Log, or stdout if it is nil.
+func (io *ioState) log(a ...interface{}) {
+	out := io.params.Log
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintln(out, a...)
+}
+
+// createOutputFile creates filename and any missing directories, refusing to overwrite it if NoClobber is set.
+func (io *ioState) createOutputFile(filename string) (*os.File, error) {
+	ioError := os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if ioError != nil {
+		return nil, ioError
+	}
 
-	fmt.Println("File", filename, "output done!")
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if io.params.NoClobber {
+		flags |= os.O_EXCL
+	}
+	return os.OpenFile(filename, flags, 0666)
 }
 
-// readPgmImage opens a pgm file and sends its data as an array of bytes.
-func (io *ioState) readPgmImage() {
+// writePgmFile writes the world to a pgm file.
+func (io *ioState) writePgmFile(filename string, world [][]byte) error {
+	file, ioError := io.createOutputFile(filename)
+	if ioError != nil {
+		return fmt.Errorf("writing %v: %w", filename, ioError)
+	}
+	defer file.Close()
+
+	ioError = writePgm(file, world)
+	if ioError == nil {
+		ioError = syncFile(file)
+	}
+	if ioError != nil {
+		return fmt.Errorf("writing %v: %w", filename, ioError)
+	}
+
+	io.log("File", filename, "output done!")
+	return nil
+}
+
+// writeCheckpointFile writes the world after turn to a checkpoint file.
+func (io *ioState) writeCheckpointFile(filename string, turn int, world [][]byte) error {
+	file, ioError := io.createOutputFile(filename)
+	if ioError != nil {
+		return fmt.Errorf("writing %v: %w", filename, ioError)
+	}
+	defer file.Close()
+
+	header := checkpointHeader{
+		Turn:    turn,
+		Width:   io.params.ImageWidth,
+		Height:  io.params.ImageHeight,
+		Turns:   io.params.Turns,
+		Threads: io.params.Threads,
+		Rule:    rule,
+	}
+	ioError = writeCheckpoint(file, header, world)
+	if ioError == nil {
+		ioError = syncFile(file)
+	}
+	if ioError != nil {
+		return fmt.Errorf("writing %v: %w", filename, ioError)
+	}
+
+	io.log("Checkpoint", filename, "output done!")
+	return nil
+}
+
+// readCheckpointFile opens a checkpoint and sends its world to the distributor row by row.
+func (io *ioState) readCheckpointFile() error {
 
 	// Request a filename from the distributor.
-	filename := <-io.channels.filename
+	filename, err := io.receiveFilename()
+	if err != nil {
+		return err
+	}
+
+	file, ioError := os.Open(filename)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
+	defer file.Close()
+
+	header, world, ioError := readCheckpoint(file)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
 
-	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
-	util.Check(ioError)
+	if header.Width != io.params.ImageWidth || header.Height != io.params.ImageHeight {
+		return fmt.Errorf("reading %v: checkpoint is %vx%v, expected %vx%v",
+			filename, header.Width, header.Height, io.params.ImageWidth, io.params.ImageHeight)
+	}
+
+	if err := io.sendWorld(world); err != nil {
+		return err
+	}
+
+	io.log("Checkpoint", filename, "input done!")
+	return nil
+}
+
+// readPgmImage opens a pgm file and streams its pixels to the distributor row by row.
+// Each row is read straight into the slice the distributor keeps, so the file is never held in memory twice.
+func (io *ioState) readPgmImage() error {
 
-	fields := strings.Fields(string(data))
+	// Request a filename from the distributor.
+	filename, err := io.receiveFilename()
+	if err != nil {
+		return err
+	}
 
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+	file, ioError := os.Open(filename)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
 	}
+	defer file.Close()
 
-	width, _ := strconv.Atoi(fields[1])
-	if width != io.params.ImageWidth {
-		panic("Incorrect width")
+	reader := bufio.NewReader(file)
+	width, height, maxval, ioError := readPgmHeader(reader)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
 	}
 
-	height, _ := strconv.Atoi(fields[2])
-	if height != io.params.ImageHeight {
-		panic("Incorrect height")
+	if width != io.params.ImageWidth || height != io.params.ImageHeight {
+		return fmt.Errorf("reading %v: image is %vx%v, expected %vx%v",
+			filename, width, height, io.params.ImageWidth, io.params.ImageHeight)
 	}
 
-	maxval, _ := strconv.Atoi(fields[3])
 	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+		return fmt.Errorf("reading %v: maxval is %v, expected 255", filename, maxval)
+	}
+
+	for y := 0; y < height; y++ {
+		row := make([]byte, width)
+		ioError = readPgmRow(reader, row)
+		if ioError != nil {
+			return fmt.Errorf("reading %v: %w", filename, ioError)
+		}
+		if err := io.sendRow(row); err != nil {
+			return err
+		}
+	}
+
+	io.log("File", filename, "input done!")
+	return nil
+}
+
+// readMacrocellFile opens a macrocell file, materialises it at the size of the world and sends it row by row.
+func (io *ioState) readMacrocellFile() error {
+
+	// Request a filename from the distributor.
+	filename, err := io.receiveFilename()
+	if err != nil {
+		return err
+	}
+
+	file, ioError := os.Open(filename)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
+	defer file.Close()
+
+	pattern, ioError := readMacrocell(file)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
 	}
 
-	image := []byte(fields[4])
+	world, ioError := pattern.materialise(io.params.ImageWidth, io.params.ImageHeight)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
 
-	for _, b := range image {
-		io.channels.input <- b
+	if err := io.sendWorld(world); err != nil {
+		return err
 	}
 
-	fmt.Println("File", filename, "input done!")
+	io.log("Macrocell", filename, "input done!")
+	return nil
+}
+
+// readRLEFile opens a run length encoded pattern and sends it to the distributor row by row.
+func (io *ioState) readRLEFile() error {
+
+	// Request a filename from the distributor.
+	filename, err := io.receiveFilename()
+	if err != nil {
+		return err
+	}
+
+	file, ioError := os.Open(filename)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
+	defer file.Close()
+
+	world, ioError := readRLE(file)
+	if ioError != nil {
+		return fmt.Errorf("reading %v: %w", filename, ioError)
+	}
+
+	if err := io.sendWorld(ResizeWorld(world, io.params.ImageWidth, io.params.ImageHeight, true)); err != nil {
+		return err
+	}
+
+	io.log("RLE", filename, "input done!")
+	return nil
+}
+
+// writeVideoFrame receives the world row by row and appends it to the video stream as one frame.
+// If the stream cannot be written it is reported with an Error event and later frames are dropped.
+func (io *ioState) writeVideoFrame() error {
+	var frame ImageOutputComplete
+	select {
+	case frame = <-io.channels.snapshot:
+	case <-io.channels.done:
+		return errStopped
+	}
+	io.videoTurn = frame.CompletedTurns
+
+	var ioError error
+	if io.video == nil && !io.videoFailed {
+		output := io.params.VideoOutput
+		if output == nil {
+			output = os.Stdout
+		}
+		if io.params.VideoFile != "-" {
+			io.videoFile, ioError = io.createOutputFile(io.params.VideoFile)
+			output = io.videoFile
+		}
+		if ioError == nil {
+			io.video = newY4mWriter(output, io.params.ImageWidth, io.params.ImageHeight, io.params.VideoScale)
+		}
+	}
+
+	if !io.videoFailed && ioError == nil {
+		ioError = io.video.beginFrame()
+	}
+	for y := 0; y < io.params.ImageHeight; y++ {
+		row, err := io.receiveRow()
+		if err != nil {
+			return err
+		}
+		// Keep receiving after a failed write so the distributor is not left waiting.
+		if !io.videoFailed && ioError == nil {
+			ioError = io.video.writeRow(row)
+		}
+	}
+	if err := io.receiveEnd(); err != nil {
+		return err
+	}
+	if !io.videoFailed && ioError == nil {
+		ioError = io.video.endFrame()
+	}
+	if ioError != nil {
+		io.videoFailed = true
+		if io.videoFile != nil {
+			io.videoFile.Close()
+		}
+		io.video = nil
+		io.videoFile = nil
+		io.report(Error{io.videoTurn, fmt.Errorf("writing %v: %w", io.params.VideoFile, ioError)})
+	}
+	return nil
+}
+
+// closeVideo closes the video file, if one was opened.
+func (io *ioState) closeVideo() {
+	videoFile := io.videoFile
+	io.video = nil
+	io.videoFile = nil
+	if videoFile != nil {
+		if ioError := videoFile.Close(); ioError != nil {
+			io.report(Error{io.videoTurn, fmt.Errorf("writing %v: %w", io.params.VideoFile, ioError)})
+			return
+		}
+		io.log("Video", io.params.VideoFile, "output done!")
+	}
 }
 
 // startIo should be the entrypoint of the io goroutine.
-func startIo(p Params, c ioChannels) {
+// It returns nil once done is closed, or an error if the world cannot be read.
+// Failed writes are reported with Error events instead.
+func startIo(p Params, c ioChannels) error {
 	io := ioState{
 		params:   p,
 		channels: c,
+		pending:  make(chan pendingSnapshot, 1),
+		free:     make(chan [][]byte, 2),
+		stopped:  make(chan struct{}),
 	}
+	io.free <- nil
+	io.free <- nil
+	writerDone := make(chan struct{})
+	go func() {
+		io.writeSnapshots()
+		close(writerDone)
+	}()
+	defer func() {
+		close(io.stopped)
+		close(io.pending)
+		<-writerDone
+		if io.videoFile != nil {
+			io.videoFile.Close()
+		}
+	}()
 
 	for {
+		var err error
 		select {
 		// Block and wait for requests from the distributor
 		case command := <-io.channels.command:
 			switch command {
 			case ioInput:
-				io.readPgmImage()
+				err = io.readPgmImage()
 			case ioOutput:
-				io.writePgmImage()
+				err = io.writePgmImage()
 			case ioCheckIdle:
-				io.channels.idle <- true
+				io.writing.Wait()
+				select {
+				case io.channels.idle <- true:
+				case <-io.channels.done:
+				}
+			case ioImport:
+				err = io.importImage()
+			case ioCheckpoint:
+				err = io.saveCheckpoint()
+			case ioResume:
+				err = io.readCheckpointFile()
+			case ioMacrocell:
+				err = io.readMacrocellFile()
+			case ioVideoFrame:
+				err = io.writeVideoFrame()
+			case ioVideoClose:
+				io.closeVideo()
+			case ioRLE:
+				err = io.readRLEFile()
 			}
+		case <-io.channels.done:
+			return nil
+		}
+		if err == errStopped {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 	}
 }
@@ -0,0 +1,238 @@
+package gol
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// benchmarkWorld returns a world with a regular pattern of alive cells.
+func benchmarkWorld(width, height int) [][]byte {
+	world := makeWorld(height, width)
+	for y := range world {
+		for x := range world[y] {
+			if (x+y)%3 == 0 {
+				world[y][x] = aliveCell
+			}
+		}
+	}
+	return world
+}
+
+// BenchmarkSnapshot measures the time the distributor spends handing a 512x512 world to the io goroutine
+// until the pgm file has been written.
+func BenchmarkSnapshot(b *testing.B) {
+	p := Params{ImageWidth: 512, ImageHeight: 512}
+	world := benchmarkWorld(p.ImageWidth, p.ImageHeight)
+	path := filepath.Join(b.TempDir(), "snapshot.pgm")
+
+	command := make(chan ioCommand)
+	idle := make(chan bool)
+	events := make(chan Event, 1)
+	snapshot := make(chan ImageOutputComplete)
+	output := make(chan []byte)
+	go startIo(p, ioChannels{command: command, idle: idle, events: events, snapshot: snapshot, output: output})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		command <- ioOutput
+		snapshot <- ImageOutputComplete{i, "snapshot", path}
+		for _, row := range world {
+			output <- row
+		}
+		output <- nil
+		command <- ioCheckIdle
+		<-idle
+		<-events
+	}
+}
+
+// BenchmarkSnapshotPerByte measures the previous protocol for comparison:
+// one channel send per cell and one unbuffered write per byte.
+func BenchmarkSnapshotPerByte(b *testing.B) {
+	p := Params{ImageWidth: 512, ImageHeight: 512}
+	world := benchmarkWorld(p.ImageWidth, p.ImageHeight)
+	path := filepath.Join(b.TempDir(), "snapshot.pgm")
+
+	output := make(chan uint8)
+	done := make(chan bool)
+	go func() {
+		for {
+			file, ioError := os.Create(path)
+			util.Check(ioError)
+			_, _ = file.WriteString("P5\n" + strconv.Itoa(p.ImageWidth) + " " + strconv.Itoa(p.ImageHeight) + "\n255\n")
+			pixels := makeWorld(p.ImageHeight, p.ImageWidth)
+			for y := range pixels {
+				for x := range pixels[y] {
+					pixels[y][x] = <-output
+				}
+			}
+			for y := range pixels {
+				for x := range pixels[y] {
+					_, ioError = file.Write([]byte{pixels[y][x]})
+					util.Check(ioError)
+				}
+			}
+			util.Check(file.Sync())
+			util.Check(file.Close())
+			done <- true
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for y := range world {
+			for x := range world[y] {
+				output <- world[y][x]
+			}
+		}
+		<-done
+	}
+}
+
+// TestSnapshotInBackground checks that snapshots are written in the background:
+// while the write of a snapshot is held up, the distributor keeps completing turns.
+func TestSnapshotInBackground(t *testing.T) {
+	syncing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer func(original func(*os.File) error) { syncFile = original }(syncFile)
+	syncFile = func(file *os.File) error {
+		select {
+		case syncing <- struct{}{}:
+		default:
+		}
+		<-release
+		return file.Sync()
+	}
+
+	p := Params{
+		Turns:     1 << 30,
+		Threads:   2,
+		InputFile: filepath.Join("..", "images", "64x64.pgm"),
+		OutputDir: t.TempDir(),
+	}
+	commands := make(chan Command, 1)
+	events := make(chan Event, 1000)
+	go RunCommands(p, events, commands)
+
+	// Events are read in the background so the distributor never waits for the test.
+	var turn int64
+	outputs := make(chan int, 10)
+	go func() {
+		for event := range events {
+			switch e := event.(type) {
+			case TurnComplete:
+				atomic.StoreInt64(&turn, int64(e.CompletedTurns))
+			case ImageOutputComplete:
+				outputs <- e.CompletedTurns
+			}
+		}
+		close(outputs)
+	}()
+
+	queued := send(t, commands, func(ack chan<- Ack) Command { return Snapshot{"", ack} })
+	select {
+	case <-syncing:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("snapshot was never written")
+	}
+
+	const turns = 100
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&turn) < int64(queued.CompletedTurns+turns) {
+		select {
+		case output := <-outputs:
+			close(release)
+			t.Fatalf("snapshot of turn %v completed while its write was held up", output)
+		default:
+		}
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatalf("turns stopped at %v while a snapshot was being written", atomic.LoadInt64(&turn))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	commands <- Kill{}
+	found := false
+	for output := range outputs {
+		if output == queued.CompletedTurns {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("snapshot of turn %v was not reported", queued.CompletedTurns)
+	}
+}
+
+// writeLargePgm writes a width x height pgm image with a diagonal stripe pattern without holding it in memory.
+func writeLargePgm(t *testing.T, path string, width, height int) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	_, _ = writer.WriteString("P5\n" + strconv.Itoa(width) + " " + strconv.Itoa(height) + "\n255\n")
+	row := make([]byte, width)
+	for y := 0; y < height; y++ {
+		for x := range row {
+			row[x] = deadCell
+			if (x+y)%7 == 0 {
+				row[x] = aliveCell
+			}
+		}
+		if _, err := writer.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReadPgmImageStreams loads a 2048x2048 image and checks that it is streamed row by row:
+// loading must allocate little more than the world itself, rather than a copy of the whole file as well.
+func TestReadPgmImageStreams(t *testing.T) {
+	const size = 2048
+	const maxRatio = 1.25
+
+	path := filepath.Join(t.TempDir(), "large.pgm")
+	writeLargePgm(t, path, size, size)
+
+	command := make(chan ioCommand)
+	filename := make(chan string)
+	input := make(chan []byte)
+	done := make(chan struct{})
+	defer close(done)
+	go startIo(Params{ImageWidth: size, ImageHeight: size}, ioChannels{command: command, filename: filename, input: input, done: done})
+
+	world := make([][]byte, size)
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	command <- ioInput
+	filename <- path
+	for y := range world {
+		world[y] = <-input
+	}
+
+	runtime.ReadMemStats(&after)
+	if ratio := float64(after.TotalAlloc-before.TotalAlloc) / (size * size); ratio > maxRatio {
+		t.Errorf("loading a %v byte world allocated %.2f times its size, expected at most %v", size*size, ratio, maxRatio)
+	}
+	if world[0][0] != aliveCell || world[size-1][size-1] != deadCell || world[1][6] != aliveCell {
+		t.Errorf("pixels were not loaded in order")
+	}
+}
@@ -0,0 +1,332 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// macrocellExtension is the extension of Golly macrocell files.
+const macrocellExtension = ".mc"
+
+// macrocellLeafLevel is the level of the 8x8 bitmap nodes that every macrocell tree bottoms out in.
+const macrocellLeafLevel = 3
+
+// macrocellNode is one line of a macrocell file.
+// Leaves hold an 8x8 bitmap with bit y*8+x set for each alive cell.
+// Other nodes hold the 1-based indices of their nw, ne, sw and se children, where 0 is an empty quadrant.
+type macrocellNode struct {
+	level    int
+	children [4]int
+	leaf     uint64
+}
+
+// macrocell is a pattern stored as a hash-consed quadtree, in the order it appears in the file.
+// The last node is the root. A quadtree engine can use the nodes directly instead of materialising them.
+type macrocell struct {
+	rule       string
+	generation int
+	nodes      []macrocellNode
+}
+
+// isMacrocellFile reports whether filename should be read as a macrocell file.
+func isMacrocellFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), macrocellExtension)
+}
+
+// root returns the 1-based index of the root node, or 0 for an empty pattern.
+func (m *macrocell) root() int {
+	return len(m.nodes)
+}
+
+// size returns the side of the square covered by the root node.
+func (m *macrocell) size() int {
+	if len(m.nodes) == 0 {
+		return 1 << macrocellLeafLevel
+	}
+	return 1 << uint(m.nodes[len(m.nodes)-1].level)
+}
+
+// checkMacrocellLevel fails if the square covered by a node of the given level has more than maxWorldCells cells.
+func checkMacrocellLevel(level int) error {
+	// Levels of 31 and above are rejected before shifting, as their side would overflow a 32-bit int.
+	if level >= 31 || checkWorldSize(1<<uint(level), 1<<uint(level)) != nil {
+		return fmt.Errorf("level %v nodes cover more than %v cells", level, maxWorldCells)
+	}
+	return nil
+}
+
+// readMacrocell parses a two-state macrocell file.
+func readMacrocell(r io.Reader) (*macrocell, error) {
+	m := &macrocell{rule: "B3/S23"}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == 1:
+			if !strings.HasPrefix(text, "[M2]") {
+				return nil, fmt.Errorf("not a macrocell file")
+			}
+		case text == "":
+		case strings.HasPrefix(text, "#R"):
+			m.rule = strings.TrimSpace(text[2:])
+		case strings.HasPrefix(text, "#G"):
+			generation, err := strconv.Atoi(strings.TrimSpace(text[2:]))
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid generation", line)
+			}
+			m.generation = generation
+		case text[0] == '#':
+		case text[0] == '.' || text[0] == '*' || text[0] == '$':
+			leaf, err := parseMacrocellLeaf(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+			m.nodes = append(m.nodes, macrocellNode{level: macrocellLeafLevel, leaf: leaf})
+		default:
+			node, err := m.parseNode(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+			m.nodes = append(m.nodes, node)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("not a macrocell file")
+	}
+	if !strings.EqualFold(strings.Replace(m.rule, "/", "", -1), "B3S23") {
+		return nil, fmt.Errorf("macrocell uses rule %v, only %v is supported", m.rule, rule)
+	}
+	return m, nil
+}
+
+// parseMacrocellLeaf parses an 8x8 bitmap such as ".*$..*$***$".
+func parseMacrocellLeaf(text string) (uint64, error) {
+	var leaf uint64
+	x, y := 0, 0
+	for _, c := range text {
+		switch c {
+		case '.', '*':
+			if x >= 8 || y >= 8 {
+				return 0, fmt.Errorf("leaf is larger than 8x8")
+			}
+			if c == '*' {
+				leaf |= 1 << uint(y*8+x)
+			}
+			x++
+		case '$':
+			x = 0
+			y++
+		default:
+			return 0, fmt.Errorf("unexpected %q in leaf", c)
+		}
+	}
+	return leaf, nil
+}
+
+// parseNode parses "level nw ne sw se", checking the children refer to earlier nodes one level down
+// and the square the node covers is small enough to be materialised.
+func (m *macrocell) parseNode(text string) (macrocellNode, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 5 {
+		return macrocellNode{}, fmt.Errorf("expected 5 fields, got %v", len(fields))
+	}
+
+	var values [5]int
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil || value < 0 {
+			return macrocellNode{}, fmt.Errorf("invalid field %q", field)
+		}
+		values[i] = value
+	}
+
+	node := macrocellNode{level: values[0]}
+	if node.level <= macrocellLeafLevel {
+		return node, fmt.Errorf("level %v nodes are not supported in two-state files", node.level)
+	}
+	if err := checkMacrocellLevel(node.level); err != nil {
+		return node, err
+	}
+	for i := range node.children {
+		child := values[i+1]
+		if child > len(m.nodes) {
+			return node, fmt.Errorf("node %v has not been defined yet", child)
+		}
+		if child != 0 && m.nodes[child-1].level != node.level-1 {
+			return node, fmt.Errorf("node %v is not at level %v", child, node.level-1)
+		}
+		node.children[i] = child
+	}
+	return node, nil
+}
+
+// materialise draws the pattern into a width x height world with the root square centred on it.
+// It fails if any alive cell falls outside the world.
+func (m *macrocell) materialise(width, height int) ([][]byte, error) {
+	if m.root() > 0 {
+		if err := checkMacrocellLevel(m.nodes[m.root()-1].level); err != nil {
+			return nil, fmt.Errorf("macrocell pattern is too large to materialise: %v", err)
+		}
+	}
+	world := makeWorld(height, width)
+	if m.root() == 0 {
+		return world, nil
+	}
+
+	offsetX := (width - m.size()) / 2
+	offsetY := (height - m.size()) / 2
+	var outside error
+	m.draw(m.root(), offsetX, offsetY, func(x, y int) {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			outside = fmt.Errorf("macrocell pattern does not fit in a %vx%v world", width, height)
+			return
+		}
+		world[y][x] = aliveCell
+	})
+	return world, outside
+}
+
+// draw calls set for every alive cell under node, whose top left corner is at (x, y).
+func (m *macrocell) draw(node, x, y int, set func(x, y int)) {
+	if node == 0 {
+		return
+	}
+	n := m.nodes[node-1]
+	if n.level == macrocellLeafLevel {
+		for i := uint(0); i < 64; i++ {
+			if n.leaf&(1<<i) != 0 {
+				set(x+int(i%8), y+int(i/8))
+			}
+		}
+		return
+	}
+	half := 1 << uint(n.level-1)
+	m.draw(n.children[0], x, y, set)
+	m.draw(n.children[1], x+half, y, set)
+	m.draw(n.children[2], x, y+half, set)
+	m.draw(n.children[3], x+half, y+half, set)
+}
+
+// newMacrocell builds a quadtree of the world, centred in the smallest square that holds it.
+// Identical subtrees are stored once.
+func newMacrocell(world [][]byte, generation int) *macrocell {
+	height := len(world)
+	width := 0
+	if height > 0 {
+		width = len(world[0])
+	}
+
+	level := macrocellLeafLevel
+	for 1<<uint(level) < width || 1<<uint(level) < height {
+		level++
+	}
+
+	builder := macrocellBuilder{
+		macrocell: &macrocell{rule: "B3/S23", generation: generation},
+		world:     world,
+		offsetX:   (1<<uint(level) - width) / 2,
+		offsetY:   (1<<uint(level) - height) / 2,
+		leaves:    make(map[uint64]int),
+		branches:  make(map[macrocellNode]int),
+	}
+	if builder.build(level, 0, 0) == 0 {
+		// Keep an empty root so the file still records the size of the world.
+		builder.nodes = append(builder.nodes, macrocellNode{level: level})
+	}
+	return builder.macrocell
+}
+
+// macrocellBuilder remembers the nodes already emitted so that repeated subtrees are shared.
+type macrocellBuilder struct {
+	*macrocell
+	world            [][]byte
+	offsetX, offsetY int
+	leaves           map[uint64]int
+	branches         map[macrocellNode]int
+}
+
+// build returns the index of the node covering the square at (x, y) in root coordinates, or 0 if it is empty.
+func (b *macrocellBuilder) build(level, x, y int) int {
+	if level == macrocellLeafLevel {
+		var leaf uint64
+		for i := uint(0); i < 64; i++ {
+			worldX, worldY := x+int(i%8)-b.offsetX, y+int(i/8)-b.offsetY
+			if worldY >= 0 && worldY < len(b.world) && worldX >= 0 && worldX < len(b.world[worldY]) &&
+				b.world[worldY][worldX] == aliveCell {
+				leaf |= 1 << i
+			}
+		}
+		if leaf == 0 {
+			return 0
+		}
+		if index, ok := b.leaves[leaf]; ok {
+			return index
+		}
+		b.nodes = append(b.nodes, macrocellNode{level: level, leaf: leaf})
+		b.leaves[leaf] = len(b.nodes)
+		return len(b.nodes)
+	}
+
+	half := 1 << uint(level-1)
+	node := macrocellNode{level: level}
+	node.children[0] = b.build(level-1, x, y)
+	node.children[1] = b.build(level-1, x+half, y)
+	node.children[2] = b.build(level-1, x, y+half)
+	node.children[3] = b.build(level-1, x+half, y+half)
+	if node.children == [4]int{} {
+		return 0
+	}
+	if index, ok := b.branches[node]; ok {
+		return index
+	}
+	b.nodes = append(b.nodes, node)
+	b.branches[node] = len(b.nodes)
+	return len(b.nodes)
+}
+
+// writeMacrocell writes the pattern in Golly's two-state macrocell format.
+func writeMacrocell(w io.Writer, m *macrocell) error {
+	writer := bufio.NewWriter(w)
+	_, _ = writer.WriteString("[M2] (gameoflife)\n")
+	_, _ = writer.WriteString("#R " + m.rule + "\n")
+	if m.generation != 0 {
+		_, _ = writer.WriteString("#G " + strconv.Itoa(m.generation) + "\n")
+	}
+	for _, node := range m.nodes {
+		if node.level == macrocellLeafLevel {
+			_, _ = writer.WriteString(formatMacrocellLeaf(node.leaf) + "\n")
+		} else {
+			_, _ = fmt.Fprintf(writer, "%v %v %v %v %v\n",
+				node.level, node.children[0], node.children[1], node.children[2], node.children[3])
+		}
+	}
+	return writer.Flush()
+}
+
+// formatMacrocellLeaf writes an 8x8 bitmap, dropping trailing dead cells and trailing empty rows.
+func formatMacrocellLeaf(leaf uint64) string {
+	var text strings.Builder
+	for y := uint(0); y < 8 && leaf>>(y*8) != 0; y++ {
+		row := (leaf >> (y * 8)) & 0xFF
+		for x := uint(0); row>>x != 0; x++ {
+			if row&(1<<x) != 0 {
+				text.WriteByte('*')
+			} else {
+				text.WriteByte('.')
+			}
+		}
+		text.WriteByte('$')
+	}
+	return text.String()
+}
@@ -0,0 +1,149 @@
+package gol
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// glider is a handcrafted macrocell file with a glider in the top left of a 16x16 square.
+const glider = `[M2] (gameoflife)
+#R B3/S23
+.*$..*$***$
+4 1 0 0 0
+`
+
+// blocks has the same 2x2 block in all four quadrants of a 32x32 square, so the leaf and
+// level 4 node are shared.
+const blocks = `[M2] (gameoflife)
+#R B3/S23
+#G 12
+$$$...**$...**$
+4 0 0 0 1
+5 2 2 2 2
+`
+
+// TestReadMacrocell checks that a handcrafted glider is materialised in the right place.
+func TestReadMacrocell(t *testing.T) {
+	pattern, err := readMacrocell(strings.NewReader(glider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := pattern.materialise(16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Params{ImageWidth: 16, ImageHeight: 16}
+	expected := []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	if alive := calculateAliveCells(p, world); !reflect.DeepEqual(alive, expected) {
+		t.Errorf("expected %v, got %v", expected, alive)
+	}
+
+	// A larger world centres the 16x16 square.
+	world, err = pattern.materialise(20, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if world[1][3] != aliveCell || world[3][2] != aliveCell {
+		t.Errorf("expected the glider to move by (2, 1) in a 20x18 world")
+	}
+
+	if _, err := pattern.materialise(8, 8); err == nil {
+		t.Errorf("expected an error when the glider does not fit")
+	}
+}
+
+// TestMacrocellFileRoundTrip checks that handcrafted files are written back unchanged.
+func TestMacrocellFileRoundTrip(t *testing.T) {
+	for _, file := range []string{glider, blocks} {
+		pattern, err := readMacrocell(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buffer bytes.Buffer
+		if err := writeMacrocell(&buffer, pattern); err != nil {
+			t.Fatal(err)
+		}
+		if buffer.String() != file {
+			t.Errorf("expected\n%v\ngot\n%v", file, buffer.String())
+		}
+	}
+}
+
+// TestMacrocellWorldRoundTrip checks that worlds survive being built into a quadtree, written and read back,
+// including sizes that are not powers of two.
+func TestMacrocellWorldRoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {64, 64}, {13, 7}, {0, 0}} {
+		world := benchmarkWorld(size[0], size[1])
+
+		var buffer bytes.Buffer
+		if err := writeMacrocell(&buffer, newMacrocell(world, 0)); err != nil {
+			t.Fatal(err)
+		}
+		pattern, err := readMacrocell(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read, err := pattern.materialise(size[0], size[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, world) {
+			t.Errorf("%vx%v world changed after round trip", size[0], size[1])
+		}
+	}
+}
+
+// TestMacrocellSharesNodes checks that repeated subtrees are only written once.
+func TestMacrocellSharesNodes(t *testing.T) {
+	pattern, err := readMacrocell(strings.NewReader(blocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := pattern.materialise(32, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := newMacrocell(world, 12); !reflect.DeepEqual(rebuilt.nodes, pattern.nodes) {
+		t.Errorf("expected %v nodes, got %v", pattern.nodes, rebuilt.nodes)
+	}
+}
+
+// tallMacrocell returns a file with a single cell in a chain of nodes up to level.
+func tallMacrocell(level int) string {
+	var b strings.Builder
+	b.WriteString("[M2] (gameoflife)\n*$\n")
+	for l := macrocellLeafLevel + 1; l <= level; l++ {
+		fmt.Fprintf(&b, "%v %v 0 0 0\n", l, l-macrocellLeafLevel)
+	}
+	return b.String()
+}
+
+// TestMacrocellTooLarge checks that trees covering more than maxWorldCells are rejected before anything is sized or allocated.
+func TestMacrocellTooLarge(t *testing.T) {
+	for _, level := range []int{15, 40, 63} {
+		t.Run(fmt.Sprint(level), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "tall.mc")
+			if err := ioutil.WriteFile(filename, []byte(tallMacrocell(level)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if width, height, err := ImageDimensions(filename); err == nil {
+				t.Errorf("expected an error, got a %vx%v world", width, height)
+			}
+
+			pattern := &macrocell{nodes: []macrocellNode{{level: macrocellLeafLevel, leaf: 1}}}
+			for l := macrocellLeafLevel + 1; l <= level; l++ {
+				pattern.nodes = append(pattern.nodes, macrocellNode{level: l, children: [4]int{pattern.root()}})
+			}
+			if _, err := pattern.materialise(16, 16); err == nil {
+				t.Error("expected materialise to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package gol
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultOutputDir      = "out"
+	defaultOutputTemplate = "{w}x{h}x{turn}"
+	defaultFrameTemplate  = "{w}x{h}x{turn:4}"
+)
+
+// rule is the name of the rule the workers implement, as used by the {rule} placeholder.
+const rule = "B3S23"
+
+// turnPlaceholder matches {turn:N}, which is replaced by the turn zero-padded to N digits.
+var turnPlaceholder = regexp.MustCompile(`\{turn:(\d+)\}`)
+
+// outputFilename expands Params.OutputTemplate for a snapshot of the world after turn.
+// The result has no extension and may contain '/' to place snapshots in subdirectories.
+func outputFilename(p Params, turn int) string {
+	if p.OutputTemplate == "" {
+		return expandTemplate(p, defaultOutputTemplate, turn)
+	}
+	return expandTemplate(p, p.OutputTemplate, turn)
+}
+
+// frameFilename expands Params.FrameTemplate for a frame of an image sequence.
+func frameFilename(p Params, turn int) string {
+	if p.FrameTemplate == "" {
+		return expandTemplate(p, defaultFrameTemplate, turn)
+	}
+	return expandTemplate(p, p.FrameTemplate, turn)
+}
+
+// expandTemplate replaces the placeholders in template with the details of the world after turn.
+func expandTemplate(p Params, template string, turn int) string {
+	template = turnPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		digits, _ := strconv.Atoi(turnPlaceholder.FindStringSubmatch(placeholder)[1])
+		return fmt.Sprintf("%0*d", digits, turn)
+	})
+	replacer := strings.NewReplacer(
+		"{w}", strconv.Itoa(p.ImageWidth),
+		"{h}", strconv.Itoa(p.ImageHeight),
+		"{turn}", strconv.Itoa(turn),
+		"{threads}", strconv.Itoa(p.Threads),
+		"{rule}", rule,
+	)
+	return replacer.Replace(template)
+}
+
+// outputPath returns the path of the pgm file a snapshot called filename is written to.
+func outputPath(p Params, filename string) string {
+	dir := p.OutputDir
+	if dir == "" {
+		dir = defaultOutputDir
+	}
+	return filepath.Join(dir, filename+".pgm")
+}
+
+// checkOutputTemplate makes sure snapshots and frames named by the templates stay inside the output directory.
+func checkOutputTemplate(p Params) error {
+	for _, filename := range []string{outputFilename(p, 0), frameFilename(p, 0)} {
+		if escapesOutputDir(filename) {
+			return fmt.Errorf("output template %q escapes the output directory", filename)
+		}
+	}
+	return nil
+}
+
+// escapesOutputDir reports whether a snapshot called filename would be written outside the output directory.
+func escapesOutputDir(filename string) bool {
+	filename = filepath.Clean(filename)
+	return filepath.IsAbs(filename) || filename == ".." || strings.HasPrefix(filename, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,83 @@
+package gol
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOutputFilename checks that every placeholder in the output template is expanded.
+func TestOutputFilename(t *testing.T) {
+	p := Params{ImageWidth: 64, ImageHeight: 32, Threads: 4, OutputTemplate: "{rule}/{w}x{h}-t{threads}/{turn}"}
+	if filename := outputFilename(p, 100); filename != "B3S23/64x32-t4/100" {
+		t.Errorf("expected B3S23/64x32-t4/100, got %v", filename)
+	}
+
+	p.OutputTemplate = ""
+	if path := outputPath(p, outputFilename(p, 7)); path != filepath.Join("out", "64x32x7.pgm") {
+		t.Errorf("expected the default template in out/, got %v", path)
+	}
+}
+
+// TestCheckOutputTemplate checks that templates cannot write outside the output directory.
+func TestCheckOutputTemplate(t *testing.T) {
+	for _, template := range []string{"{w}x{h}x{turn}", "runs/{turn}", "a/../{turn}"} {
+		if err := checkOutputTemplate(Params{OutputTemplate: template}); err != nil {
+			t.Errorf("%v: unexpected error %v", template, err)
+		}
+	}
+	for _, template := range []string{"../{turn}", "/tmp/{turn}", "a/../../{turn}"} {
+		if err := checkOutputTemplate(Params{OutputTemplate: template}); err == nil {
+			t.Errorf("%v: expected an error", template)
+		}
+	}
+}
+
+// TestFrameFilename checks that {turn:N} pads the turn to N digits.
+func TestFrameFilename(t *testing.T) {
+	p := Params{ImageWidth: 512, ImageHeight: 512}
+	if filename := frameFilename(p, 100); filename != "512x512x0100" {
+		t.Errorf("expected 512x512x0100, got %v", filename)
+	}
+	p.FrameTemplate = "frames/{turn:6}-{turn}"
+	if filename := frameFilename(p, 42); filename != "frames/000042-42" {
+		t.Errorf("expected frames/000042-42, got %v", filename)
+	}
+}
+
+// TestFrameEvery checks that a frame is written every FrameEvery turns alongside keypress snapshots.
+func TestFrameEvery(t *testing.T) {
+	p := Params{
+		Turns:      10,
+		Threads:    2,
+		InputFile:  filepath.Join("..", "images", "16x16.pgm"),
+		OutputDir:  t.TempDir(),
+		FrameEvery: 4,
+	}
+	keyPresses := make(chan rune, 3)
+	keyPresses <- 's'
+	keyPresses <- 's'
+	keyPresses <- 's'
+
+	events := make(chan Event, 1000)
+	go Run(p, events, keyPresses)
+	var frames, snapshots []int
+	for event := range events {
+		if e, ok := event.(ImageOutputComplete); ok {
+			if strings.HasSuffix(e.Filename, fmt.Sprintf("x%04d", e.CompletedTurns)) {
+				frames = append(frames, e.CompletedTurns)
+			} else {
+				snapshots = append(snapshots, e.CompletedTurns)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(frames, []int{0, 4, 8}) {
+		t.Errorf("expected frames at turns [0 4 8], got %v", frames)
+	}
+	if len(snapshots) != 4 {
+		t.Errorf("expected 3 keypress snapshots and a final one, got %v", snapshots)
+	}
+}
@@ -0,0 +1,167 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rleExtension is the extension of run length encoded pattern files.
+const rleExtension = ".rle"
+
+// rleLineLength is the longest line writeRLE produces, as recommended by the format.
+const rleLineLength = 70
+
+// rleHeader matches the "x = 3, y = 3, rule = B3/S23" line of an RLE file.
+var rleHeader = regexp.MustCompile(`^x\s*=\s*(\d+)\s*,\s*y\s*=\s*(\d+)(?:\s*,\s*rule\s*=\s*(\S+))?`)
+
+// isRLEFile reports whether filename should be read as a run length encoded pattern.
+func isRLEFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), rleExtension)
+}
+
+// readRLEHeader skips '#' comment lines and returns the size of the pattern,
+// leaving r positioned at the start of the encoded cells.
+func readRLEHeader(r *bufio.Reader) (width, height int, err error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return 0, 0, fmt.Errorf("missing RLE header")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		match := rleHeader.FindStringSubmatch(line)
+		if match == nil {
+			return 0, 0, fmt.Errorf("invalid RLE header %q", line)
+		}
+		if match[3] != "" && !strings.EqualFold(strings.Replace(match[3], "/", "", -1), "B3S23") {
+			return 0, 0, fmt.Errorf("RLE uses rule %v, only %v is supported", match[3], rule)
+		}
+		width, _ = strconv.Atoi(match[1])
+		height, _ = strconv.Atoi(match[2])
+		if err := checkWorldSize(width, height); err != nil {
+			return 0, 0, fmt.Errorf("invalid RLE size: %v", err)
+		}
+		return width, height, nil
+	}
+}
+
+// readRLE reads a run length encoded pattern into a world the size given in its header.
+func readRLE(r io.Reader) ([][]byte, error) {
+	reader := bufio.NewReader(r)
+	width, height, err := readRLEHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	world := makeWorld(height, width)
+	x, y, count := 0, 0, 0
+	for {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			return world, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case c >= '0' && c <= '9':
+			count = count*10 + int(c-'0')
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '!':
+			return world, nil
+		}
+
+		run := count
+		if run == 0 {
+			run = 1
+		}
+		count = 0
+
+		switch {
+		case c == '$':
+			x, y = 0, y+run
+		case c == 'b' || c == '.':
+			x += run
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			if y >= height || x+run > width {
+				return nil, fmt.Errorf("RLE pattern is larger than its %vx%v header", width, height)
+			}
+			for i := 0; i < run; i++ {
+				world[y][x+i] = aliveCell
+			}
+			x += run
+		default:
+			return nil, fmt.Errorf("unexpected %q in RLE pattern", c)
+		}
+	}
+}
+
+// writeRLE writes the world as a run length encoded pattern, omitting trailing dead cells and rows.
+func writeRLE(w io.Writer, world [][]byte) error {
+	height := len(world)
+	width := 0
+	if height > 0 {
+		width = len(world[0])
+	}
+
+	writer := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(writer, "x = %v, y = %v, rule = B3/S23\n", width, height)
+
+	line := 0
+	emit := func(run int, tag byte) {
+		token := string(tag)
+		if run > 1 {
+			token = strconv.Itoa(run) + token
+		}
+		if line+len(token) > rleLineLength {
+			_, _ = writer.WriteString("\n")
+			line = 0
+		}
+		_, _ = writer.WriteString(token)
+		line += len(token)
+	}
+
+	emptyRows := 0
+	for _, row := range world {
+		end := len(row)
+		for end > 0 && row[end-1] != aliveCell {
+			end--
+		}
+		if end == 0 {
+			emptyRows++
+			continue
+		}
+		if emptyRows > 0 {
+			emit(emptyRows, '$')
+			emptyRows = 0
+		}
+
+		for x := 0; x < end; {
+			run := 1
+			for x+run < end && (row[x+run] == aliveCell) == (row[x] == aliveCell) {
+				run++
+			}
+			if row[x] == aliveCell {
+				emit(run, 'o')
+			} else {
+				emit(run, 'b')
+			}
+			x += run
+		}
+		emptyRows = 1
+	}
+	emit(1, '!')
+	_, _ = writer.WriteString("\n")
+	return writer.Flush()
+}
@@ -0,0 +1,58 @@
+package gol
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestReadRLE checks a handcrafted glider with comments, multi-digit runs and blank rows.
+func TestReadRLE(t *testing.T) {
+	file := "#N Glider\n#C A comment\nx = 12, y = 5, rule = B3/S23\nbo$2bo$3o2$10b2o!\n"
+	world, err := readRLE(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Params{ImageWidth: 12, ImageHeight: 5}
+	alive := calculateAliveCells(p, world)
+	if len(alive) != 7 || world[0][1] != aliveCell || world[2][2] != aliveCell || world[4][11] != aliveCell {
+		t.Errorf("unexpected alive cells %v", alive)
+	}
+
+	if _, err := readRLE(strings.NewReader("x = 2, y = 1\n3o!")); err == nil {
+		t.Errorf("expected an error for a row longer than the header")
+	}
+	if _, err := readRLE(strings.NewReader("x = 2, y = 1, rule = B36/S23\no!")); err == nil {
+		t.Errorf("expected an error for an unsupported rule")
+	}
+	if _, err := readRLE(strings.NewReader("x = 0, y = 1\n!")); err == nil {
+		t.Errorf("expected an error for an empty pattern")
+	}
+	if _, err := readRLE(strings.NewReader("x = 1000000, y = 1000000\n!")); err == nil {
+		t.Errorf("expected an error for a pattern larger than maxWorldCells")
+	}
+}
+
+// TestRLERoundTrip checks that writing and reading a world gives back the same world.
+func TestRLERoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {100, 3}, {5, 9}} {
+		world := benchmarkWorld(size[0], size[1])
+		var buffer bytes.Buffer
+		if err := writeRLE(&buffer, world); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(buffer.String(), "\n") {
+			if len(line) > rleLineLength {
+				t.Errorf("line longer than %v characters: %q", rleLineLength, line)
+			}
+		}
+		read, err := readRLE(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, world) {
+			t.Errorf("%vx%v world changed after round trip", size[0], size[1])
+		}
+	}
+}
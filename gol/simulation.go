@@ -0,0 +1,177 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Simulation is a Game of Life world that is evolved by calling its methods, for tools that want to use the
+// engine as a library. Each Step splits the world between the same workers Run uses, but nothing runs between calls
+// and there are no channels to read. A Simulation is not safe for concurrent use.
+type Simulation struct {
+	params Params
+	world  [][]byte
+	turn   int
+}
+
+// NewSimulation returns a width x height world with every cell dead. Turns are split between threads workers.
+// It panics if width or height is not positive.
+func NewSimulation(width, height, threads int) *Simulation {
+	if err := checkSimulationSize(width, height); err != nil {
+		panic(fmt.Sprintf("NewSimulation: %v", err))
+	}
+	return newSimulation(Params{Threads: threads, ImageWidth: width, ImageHeight: height}, makeWorld(height, width), 0)
+}
+
+// NewSimulationFromWorld starts from a copy of world, such as one returned by ReadWorld,
+// which has already completed turn turns. It panics if the world is empty or its rows differ in length.
+func NewSimulationFromWorld(world [][]byte, turn, threads int) *Simulation {
+	if err := checkSimulationWorld(world); err != nil {
+		panic(fmt.Sprintf("NewSimulationFromWorld: %v", err))
+	}
+	width, height := worldSize(world)
+	return newSimulation(Params{Threads: threads, ImageWidth: width, ImageHeight: height}, copyWorld(world), turn)
+}
+
+// LoadSimulation reads a world with ReadWorld, using the default import threshold for images.
+func LoadSimulation(filename string, threads int) (*Simulation, error) {
+	world, turn, err := ReadWorld(filename, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSimulationWorld(world); err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	width, height := worldSize(world)
+	return newSimulation(Params{Threads: threads, ImageWidth: width, ImageHeight: height}, world, turn), nil
+}
+
+// checkSimulationSize fails if a width x height world would have no cells to wrap coordinates onto.
+func checkSimulationSize(width, height int) error {
+	if width < 1 || height < 1 {
+		return fmt.Errorf("world must be at least 1x1, not %vx%v", width, height)
+	}
+	return nil
+}
+
+// checkSimulationWorld fails if world is empty or not rectangular.
+func checkSimulationWorld(world [][]byte) error {
+	width, height := worldSize(world)
+	if err := checkSimulationSize(width, height); err != nil {
+		return err
+	}
+	for y, row := range world {
+		if len(row) != width {
+			return fmt.Errorf("row %v is %v cells wide, not %v", y, len(row), width)
+		}
+	}
+	return nil
+}
+
+// newSimulation wraps world without copying it. Only Threads, ImageWidth and ImageHeight of p are used.
+func newSimulation(p Params, world [][]byte, turn int) *Simulation {
+	return &Simulation{params: p, world: world, turn: turn}
+}
+
+// Width returns the width of the world.
+func (s *Simulation) Width() int {
+	return s.params.ImageWidth
+}
+
+// Height returns the height of the world.
+func (s *Simulation) Height() int {
+	return s.params.ImageHeight
+}
+
+// Turn returns the number of completed turns.
+func (s *Simulation) Turn() int {
+	return s.turn
+}
+
+// Step evolves the world by n turns.
+func (s *Simulation) Step(n int) {
+	for i := 0; i < n; i++ {
+		s.world = calculateNextTurn(s.params, s.world)
+		s.turn++
+	}
+}
+
+// advance evolves the world by one turn and returns the cells that changed.
+func (s *Simulation) advance() []util.Cell {
+	next := calculateNextTurn(s.params, s.world)
+	flipped := diffWorlds(s.params, s.world, next)
+	s.world = next
+	s.turn++
+	return flipped
+}
+
+// Alive reports whether the cell at (x, y) is alive. Coordinates wrap around the edges like the world does.
+func (s *Simulation) Alive(x, y int) bool {
+	x, y = s.wrap(x, y)
+	return s.world[y][x] == aliveCell
+}
+
+// Set makes the cell at (x, y) alive or dead. Coordinates wrap around the edges.
+func (s *Simulation) Set(x, y int, alive bool) {
+	x, y = s.wrap(x, y)
+	s.world[y][x] = deadCell
+	if alive {
+		s.world[y][x] = aliveCell
+	}
+}
+
+// Stamp copies pattern into the world with its top left corner at (x, y), wrapping around the edges.
+// Dead cells in the pattern are copied too, so whatever was underneath is replaced.
+func (s *Simulation) Stamp(pattern [][]byte, x, y int) {
+	for dy, row := range pattern {
+		for dx, cell := range row {
+			s.Set(x+dx, y+dy, cell == aliveCell)
+		}
+	}
+}
+
+// AliveCells returns the coordinates of every alive cell, row by row.
+func (s *Simulation) AliveCells() []util.Cell {
+	return calculateAliveCells(s.params, s.world)
+}
+
+// Population returns the number of alive cells.
+func (s *Simulation) Population() int {
+	population := 0
+	for _, row := range s.world {
+		for _, cell := range row {
+			if cell == aliveCell {
+				population++
+			}
+		}
+	}
+	return population
+}
+
+// World returns a copy of the world, which can be written with WriteWorld.
+func (s *Simulation) World() [][]byte {
+	return copyWorld(s.world)
+}
+
+// wrap maps any coordinates onto the world.
+func (s *Simulation) wrap(x, y int) (int, int) {
+	x %= s.params.ImageWidth
+	if x < 0 {
+		x += s.params.ImageWidth
+	}
+	y %= s.params.ImageHeight
+	if y < 0 {
+		y += s.params.ImageHeight
+	}
+	return x, y
+}
+
+// copyWorld returns a copy of world that shares no rows with it.
+func copyWorld(world [][]byte) [][]byte {
+	copied := make([][]byte, len(world))
+	for y, row := range world {
+		copied[y] = append([]byte(nil), row...)
+	}
+	return copied
+}
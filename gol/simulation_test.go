@@ -0,0 +1,80 @@
+package gol
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestSimulationBlinker stamps a blinker across the edge of the world and checks that it oscillates.
+func TestSimulationBlinker(t *testing.T) {
+	s := NewSimulation(8, 8, 2)
+	blinker := [][]byte{{aliveCell, aliveCell, aliveCell}}
+	s.Stamp(blinker, -1, 3)
+
+	horizontal := []util.Cell{{X: 0, Y: 3}, {X: 1, Y: 3}, {X: 7, Y: 3}}
+	if alive := s.AliveCells(); !reflect.DeepEqual(alive, horizontal) {
+		t.Fatalf("expected %v after stamping, got %v", horizontal, alive)
+	}
+
+	s.Step(1)
+	if !s.Alive(0, 2) || !s.Alive(0, 4) || s.Alive(1, 3) || s.Population() != 3 {
+		t.Errorf("expected a vertical blinker at x 0, got %v", s.AliveCells())
+	}
+
+	s.Step(1)
+	if alive := s.AliveCells(); !reflect.DeepEqual(alive, horizontal) || s.Turn() != 2 {
+		t.Errorf("expected %v after turn 2, got %v after turn %v", horizontal, alive, s.Turn())
+	}
+}
+
+// TestSimulationMatchesRun checks that stepping a loaded world gives the same result as Run.
+func TestSimulationMatchesRun(t *testing.T) {
+	p := Params{
+		Turns:     100,
+		Threads:   4,
+		InputFile: filepath.Join("..", "images", "64x64.pgm"),
+		OutputDir: t.TempDir(),
+	}
+	final, _ := runToCompletion(p)
+
+	s, err := LoadSimulation(p.InputFile, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Step(p.Turns)
+	if alive := s.AliveCells(); !reflect.DeepEqual(alive, final.Alive) {
+		t.Errorf("simulation has %v alive cells after %v turns, Run has %v", len(alive), p.Turns, len(final.Alive))
+	}
+}
+
+// TestSimulationWorldIsCopied checks that the world passed in and handed out cannot change the simulation.
+func TestSimulationWorldIsCopied(t *testing.T) {
+	world := makeWorld(4, 4)
+	s := NewSimulationFromWorld(world, 10, 1)
+	world[0][0] = aliveCell
+	s.World()[1][1] = aliveCell
+	if s.Population() != 0 || s.Turn() != 10 {
+		t.Errorf("expected an empty world at turn 10, got %v cells at turn %v", s.Population(), s.Turn())
+	}
+}
+
+// TestSimulationEmptyWorld checks that a world without cells is rejected when the simulation is created,
+// rather than dividing by zero when a cell is looked up.
+func TestSimulationEmptyWorld(t *testing.T) {
+	panics := func(name string, create func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected %v to panic", name)
+			}
+		}()
+		create()
+	}
+	panics("NewSimulation(0, 8)", func() { NewSimulation(0, 8, 1) })
+	panics("NewSimulation(8, -1)", func() { NewSimulation(8, -1, 1) })
+	panics("NewSimulationFromWorld(nil)", func() { NewSimulationFromWorld(nil, 0, 1) })
+	panics("NewSimulationFromWorld(4x0)", func() { NewSimulationFromWorld(makeWorld(4, 0), 0, 1) })
+	panics("NewSimulationFromWorld(ragged)", func() { NewSimulationFromWorld([][]byte{make([]byte, 4), make([]byte, 3)}, 0, 1) })
+}
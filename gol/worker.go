@@ -0,0 +1,45 @@
+package gol
+
+// Cell values as they appear in the world and in pgm images.
+const (
+	deadCell  byte = 0x00
+	aliveCell byte = 0xFF
+)
+
+// worker evolves rows [startY, endY) of the world by one turn and sends the new strip back.
+func worker(p Params, world [][]byte, startY, endY int, out chan<- [][]byte) {
+	out <- calculateNextStrip(p, world, startY, endY)
+}
+
+// calculateNextStrip applies the Game of Life rules to rows [startY, endY) of a wrapping world.
+func calculateNextStrip(p Params, world [][]byte, startY, endY int) [][]byte {
+	strip := makeWorld(endY-startY, p.ImageWidth)
+	for y := startY; y < endY; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			neighbours := countNeighbours(p, world, x, y)
+			alive := world[y][x] == aliveCell
+			if neighbours == 3 || (alive && neighbours == 2) {
+				strip[y-startY][x] = aliveCell
+			}
+		}
+	}
+	return strip
+}
+
+// countNeighbours counts the alive cells around (x, y), wrapping at the edges of the world.
+func countNeighbours(p Params, world [][]byte, x, y int) int {
+	count := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			ny := (y + dy + p.ImageHeight) % p.ImageHeight
+			nx := (x + dx + p.ImageWidth) % p.ImageWidth
+			if world[ny][nx] == aliveCell {
+				count++
+			}
+		}
+	}
+	return count
+}
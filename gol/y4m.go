@@ -0,0 +1,63 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// y4mWriter streams greyscale frames in the YUV4MPEG2 format, so they can be piped into an encoder such as ffmpeg.
+// Each cell becomes a scale x scale block of pixels.
+type y4mWriter struct {
+	writer        *bufio.Writer
+	width, height int
+	scale         int
+	wroteHeader   bool
+	scaled        []byte
+}
+
+func newY4mWriter(w io.Writer, width, height, scale int) *y4mWriter {
+	if scale < 1 {
+		scale = 1
+	}
+	return &y4mWriter{
+		writer: bufio.NewWriter(w),
+		width:  width,
+		height: height,
+		scale:  scale,
+		scaled: make([]byte, width*scale),
+	}
+}
+
+// beginFrame starts a frame, writing the stream header first if this is the first frame.
+func (v *y4mWriter) beginFrame() error {
+	if !v.wroteHeader {
+		v.wroteHeader = true
+		_, err := fmt.Fprintf(v.writer, "YUV4MPEG2 W%d H%d F30:1 Ip A1:1 Cmono\n", v.width*v.scale, v.height*v.scale)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := v.writer.WriteString("FRAME\n")
+	return err
+}
+
+// writeRow writes the next row of cells. A frame is complete after height rows.
+func (v *y4mWriter) writeRow(row []byte) error {
+	for x, cell := range row {
+		for i := 0; i < v.scale; i++ {
+			v.scaled[x*v.scale+i] = cell
+		}
+	}
+	for i := 0; i < v.scale; i++ {
+		if _, err := v.writer.Write(v.scaled); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endFrame flushes the frame so that a reader on the other end of a pipe sees it straight away.
+func (v *y4mWriter) endFrame() error {
+	return v.writer.Flush()
+}
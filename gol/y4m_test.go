@@ -0,0 +1,113 @@
+package gol
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestY4mWriter checks the exact bytes of a two frame, 2x upscaled stream.
+func TestY4mWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	video := newY4mWriter(&buffer, 2, 1, 2)
+	for _, row := range [][]byte{{aliveCell, deadCell}, {deadCell, aliveCell}} {
+		if err := video.beginFrame(); err != nil {
+			t.Fatal(err)
+		}
+		if err := video.writeRow(row); err != nil {
+			t.Fatal(err)
+		}
+		if err := video.endFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "YUV4MPEG2 W4 H2 F30:1 Ip A1:1 Cmono\n" +
+		"FRAME\n\xff\xff\x00\x00\xff\xff\x00\x00" +
+		"FRAME\n\x00\x00\xff\xff\x00\x00\xff\xff"
+	if buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+// TestVideoEvery checks that a run streams the initial world and every VideoEvery-th turn.
+func TestVideoEvery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.y4m")
+	p := Params{
+		Turns:      10,
+		Threads:    2,
+		InputFile:  filepath.Join("..", "images", "16x16.pgm"),
+		OutputDir:  t.TempDir(),
+		VideoFile:  path,
+		VideoEvery: 5,
+		VideoScale: 3,
+	}
+	runToCompletion(p)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "YUV4MPEG2 W48 H48 F30:1 Ip A1:1 Cmono\n"
+	frames := bytes.Count(data, []byte("FRAME\n"))
+	if !bytes.HasPrefix(data, []byte(header)) || frames != 3 || len(data) != len(header)+3*(6+48*48) {
+		t.Errorf("expected 3 frames of 48x48 after the header, got %v frames in %v bytes", frames, len(data))
+	}
+}
+
+// TestVideoOutput checks that a video named "-" is streamed to VideoOutput and the log is kept out of it.
+func TestVideoOutput(t *testing.T) {
+	var video, log bytes.Buffer
+	p := Params{
+		Turns:       10,
+		Threads:     2,
+		InputFile:   filepath.Join("..", "images", "16x16.pgm"),
+		OutputDir:   t.TempDir(),
+		VideoFile:   "-",
+		VideoOutput: &video,
+		VideoEvery:  5,
+		Log:         &log,
+	}
+	runToCompletion(p)
+
+	if frames := bytes.Count(video.Bytes(), []byte("FRAME\n")); frames != 3 {
+		t.Errorf("expected 3 frames, got %v", frames)
+	}
+	if bytes.Contains(video.Bytes(), []byte("done!")) || !bytes.Contains(log.Bytes(), []byte("input done!")) {
+		t.Errorf("expected the log to be written to Log, got %q", log.String())
+	}
+}
+
+// TestVideoNoClobber checks that an existing video is reported and left alone unless overwriting is allowed.
+func TestVideoNoClobber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.y4m")
+	if err := ioutil.WriteFile(path, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	p := Params{
+		Turns:     1,
+		Threads:   2,
+		InputFile: filepath.Join("..", "images", "16x16.pgm"),
+		OutputDir: t.TempDir(),
+		VideoFile: path,
+		NoClobber: true,
+		Log:       ioutil.Discard,
+	}
+	events := make(chan Event, 1000)
+	go Run(p, events, nil)
+	var reported error
+	for event := range events {
+		if e, ok := event.(Error); ok {
+			reported = e.Err
+		}
+	}
+	if !errors.Is(reported, os.ErrExist) {
+		t.Errorf("expected an error saying the video exists, got %v", reported)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "old" {
+		t.Errorf("expected the video to be left alone, got %q, %v", data, err)
+	}
+}
@@ -3,14 +3,22 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
 
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/vis"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
+// 'go run . convert' converts between world formats instead.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		convert(os.Args[2:])
+		return
+	}
+
 	runtime.LockOSThread()
 	var params gol.Params
 
@@ -38,31 +46,172 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.StringVar(
+		&params.InputFile,
+		"in",
+		"",
+		"Specify a pgm, png, jpeg, gif, macrocell (.mc) or RLE file to open at its own size. Overrides -w and -h.")
+
+	flag.StringVar(
+		&params.ImportFile,
+		"import",
+		"",
+		"Specify a png, jpeg or gif image to seed the world from. Defaults to images/<w>x<h>.pgm.")
+
+	threshold := flag.Uint(
+		"threshold",
+		128,
+		"Specify the brightness (1-255) at which an imported pixel becomes alive. Defaults to 128.")
+
+	flag.BoolVar(
+		&params.ImportDither,
+		"dither",
+		false,
+		"Convert imported images with Floyd-Steinberg dithering instead of a plain threshold.")
+
+	flag.StringVar(
+		&params.OutputDir,
+		"out",
+		"out",
+		"Specify the directory snapshots are written to. Defaults to out.")
+
+	flag.StringVar(
+		&params.OutputTemplate,
+		"template",
+		"{w}x{h}x{turn}",
+		"Specify how snapshots are named using {w}, {h}, {turn}, {turn:N}, {threads} and {rule}. Defaults to {w}x{h}x{turn}.")
+
+	flag.IntVar(
+		&params.FrameEvery,
+		"frames",
+		0,
+		"Write a numbered frame every N turns for making animations. Defaults to 0 (no frames).")
+
+	flag.StringVar(
+		&params.FrameTemplate,
+		"frameTemplate",
+		"{w}x{h}x{turn:4}",
+		"Specify how frames are named, like -template. Defaults to {w}x{h}x{turn:4}.")
+
+	flag.StringVar(
+		&params.VideoFile,
+		"video",
+		"",
+		"Stream the run as a greyscale Y4M video to this file, or to stdout if it is -.")
+
+	flag.IntVar(
+		&params.VideoEvery,
+		"videoEvery",
+		1,
+		"Add a video frame every N turns. Defaults to 1.")
+
+	flag.IntVar(
+		&params.VideoScale,
+		"videoScale",
+		1,
+		"Draw each cell as an NxN block of pixels in the video. Defaults to 1.")
+
+	overwrite := flag.Bool(
+		"overwrite",
+		false,
+		"Allow snapshots, frames and videos to overwrite existing files.")
+
+	flag.IntVar(
+		&params.CheckpointEvery,
+		"checkpoint",
+		0,
+		"Write a checkpoint every N turns. Defaults to 0 (only when 'c' is pressed).")
+
+	flag.StringVar(
+		&params.ResumeFile,
+		"resume",
+		"",
+		"Specify a checkpoint to resume from. Overrides -w, -h and -in.")
+
+	flag.Float64Var(
+		&params.FrameRate,
+		"fps",
+		0,
+		"Specify a frame rate for the window. Turns between frames are run but not drawn. Defaults to 0, which draws every turn.")
+
 	noVis := flag.Bool(
 		"noVis",
 		false,
 		"Disables the SDL window, so there is no visualisation during the tests.")
 
+	term := flag.Bool(
+		"term",
+		false,
+		"Draws the world in the terminal with braille characters instead of opening the SDL window.")
+
 	flag.Parse()
 
-	fmt.Println("Threads:", params.Threads)
-	fmt.Println("Width:", params.ImageWidth)
-	fmt.Println("Height:", params.ImageHeight)
+	if params.VideoFile == "-" && *term && !*noVis {
+		fmt.Fprintln(os.Stderr, "-video - and -term cannot both use stdout")
+		os.Exit(1)
+	}
+	params.Log = os.Stdout
+	if params.VideoFile == "-" {
+		// The video owns stdout, so log to stderr.
+		params.Log = os.Stderr
+	}
+	if *threshold < 1 || *threshold > 255 {
+		fmt.Fprintf(os.Stderr, "-threshold must be between 1 and 255, not %v\n", *threshold)
+		os.Exit(1)
+	}
+	params.ImportThreshold = uint8(*threshold)
+	params.NoClobber = !*overwrite
+	// Every Renderer accepts one CellsFlipped event per turn or frame.
+	params.BatchFlips = true
+	// The window's graph panel plots the statistics of every turn.
+	params.ReportStatistics = !*noVis && !*term
+
+	if params.InputFile != "" {
+		width, height, err := gol.ImageDimensions(params.InputFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		params.ImageWidth, params.ImageHeight = width, height
+	}
+	if params.ResumeFile != "" {
+		turn, width, height, err := gol.CheckpointInfo(params.ResumeFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		params.ImageWidth, params.ImageHeight = width, height
+		fmt.Fprintln(params.Log, "Resuming from turn", turn)
+	}
 
-	keyPresses := make(chan rune, 10)
+	fmt.Fprintln(params.Log, "Threads:", params.Threads)
+	fmt.Fprintln(params.Log, "Width:", params.ImageWidth)
+	fmt.Fprintln(params.Log, "Height:", params.ImageHeight)
+
+	commands := make(chan gol.Command, 10)
 	events := make(chan gol.Event, 1000)
 
-	go gol.Run(params, events, keyPresses)
-	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
-	} else {
-		complete := false
-		for !complete {
-			event := <-events
-			switch event.(type) {
-			case gol.FinalTurnComplete:
-				complete = true
-			}
+	// The renderer is ready before the run starts, so a terminal can take over stdout before anything is logged.
+	var renderer vis.Renderer = vis.NullRenderer{}
+	switch {
+	case *noVis:
+	case *term:
+		terminal, err := vis.NewTerminalRenderer(params.ImageWidth, params.ImageHeight)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		renderer = terminal
+	default:
+		renderer = sdl.NewWindowRenderer(params.ImageWidth, params.ImageHeight)
+	}
+	if logger, ok := renderer.(vis.Logger); ok {
+		params.Log = logger.Log()
+	}
+
+	go gol.RunCommands(params, events, commands)
+	if !vis.RunRenderer(renderer, events, commands) {
+		// The world could not be loaded, and the reason has been printed.
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,146 @@
+package sdl
+
+import "math"
+
+// ColourMode chooses how the window colours cells.
+type ColourMode int
+
+const (
+	// Mono draws alive cells white and dead cells black.
+	Mono ColourMode = iota
+	// Age colours alive cells from yellow when they are born to blue once they have lived for ageTurns.
+	Age
+	// Trails draws alive cells white and fades cells that died in the last trailTurns from red to black.
+	Trails
+	// Heatmap colours every cell by how often turns have flipped it, on a log scale. Alive cells are white.
+	Heatmap
+	colourModes
+)
+
+func (m ColourMode) String() string {
+	switch m {
+	case Mono:
+		return "Mono"
+	case Age:
+		return "Age"
+	case Trails:
+		return "Trails"
+	case Heatmap:
+		return "Heatmap"
+	default:
+		return "Unknown"
+	}
+}
+
+// Next returns the mode after m, wrapping round to Mono.
+func (m ColourMode) Next() ColourMode {
+	return (m + 1) % colourModes
+}
+
+const (
+	ageTurns   = 100
+	trailTurns = 16
+)
+
+type colour struct {
+	r, g, b uint8
+}
+
+var (
+	black = colour{0, 0, 0}
+	white = colour{0xFF, 0xFF, 0xFF}
+)
+
+// cellHistory is what the window remembers about every cell from the flips it has been sent.
+type cellHistory struct {
+	alive    []bool
+	changed  []int // the turn each cell was last born, died or was edited in
+	activity []uint32
+	maxFlips uint32
+	// population is the number of alive cells.
+	population int
+	// approximate is set once a flip covered more than one turn. Cells that flipped and flipped back
+	// in the turns that were not shown are missed, so ages, trails and activity are only roughly right.
+	approximate bool
+}
+
+func newCellHistory(cells int) *cellHistory {
+	return &cellHistory{
+		alive:    make([]bool, cells),
+		changed:  make([]int, cells),
+		activity: make([]uint32, cells),
+	}
+}
+
+// flip records that cell i was born or died by turn, over turns turns. Flips over no turns, from loading
+// or editing the world, change the cell without counting as activity.
+func (h *cellHistory) flip(i, turn, turns int) {
+	h.alive[i] = !h.alive[i]
+	if h.alive[i] {
+		h.population++
+	} else {
+		h.population--
+	}
+	h.changed[i] = turn
+	if turns > 1 {
+		h.approximate = true
+	}
+	if turns == 0 {
+		return
+	}
+	h.activity[i]++
+	if h.activity[i] > h.maxFlips {
+		h.maxFlips = h.activity[i]
+	}
+}
+
+// colour returns the colour of cell i in mode at turn.
+func (h *cellHistory) colour(i, turn int, mode ColourMode) colour {
+	switch mode {
+	case Age:
+		if !h.alive[i] {
+			return black
+		}
+		age := float64(turn-h.changed[i]) / ageTurns
+		return blend(colour{0xFF, 0xE0, 0x20}, colour{0x20, 0x40, 0xFF}, age)
+	case Trails:
+		if h.alive[i] {
+			return white
+		}
+		since := turn - h.changed[i]
+		if h.activity[i] == 0 || since >= trailTurns {
+			return black
+		}
+		return blend(colour{0xFF, 0x30, 0x10}, black, float64(since+1)/trailTurns)
+	case Heatmap:
+		if h.alive[i] {
+			return white
+		}
+		if h.activity[i] == 0 {
+			return black
+		}
+		return heat(math.Log(float64(h.activity[i])+1) / math.Log(float64(h.maxFlips)+1))
+	default:
+		if h.alive[i] {
+			return white
+		}
+		return black
+	}
+}
+
+// blend mixes from into to by t, which is clamped to between 0 and 1.
+func blend(from, to colour, t float64) colour {
+	t = math.Max(0, math.Min(1, t))
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t)
+	}
+	return colour{mix(from.r, to.r), mix(from.g, to.g), mix(from.b, to.b)}
+}
+
+// heat maps t between 0 and 1 from dark red through red and orange to yellow.
+func heat(t float64) colour {
+	if t < 0.5 {
+		return blend(colour{0x40, 0, 0}, colour{0xFF, 0x20, 0}, 2*t)
+	}
+	return blend(colour{0xFF, 0x20, 0}, colour{0xFF, 0xE0, 0x40}, 2*t-1)
+}
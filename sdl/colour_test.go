@@ -0,0 +1,25 @@
+package sdl
+
+import "testing"
+
+// TestCellHistory checks that only flips made by turns count as activity,
+// and that flips over several turns mark the history as approximate.
+func TestCellHistory(t *testing.T) {
+	h := newCellHistory(2)
+	h.flip(0, 0, 0)
+	h.flip(1, 3, 1)
+	h.flip(1, 4, 0)
+	if h.activity[0] != 0 || h.activity[1] != 1 || h.maxFlips != 1 {
+		t.Errorf("expected only the flip by a turn to count, got activity %v", h.activity)
+	}
+	if h.changed[1] != 4 || h.population != 1 {
+		t.Errorf("expected cell 1 to be edited dead in turn 4, got turn %v and population %v", h.changed[1], h.population)
+	}
+	if h.approximate {
+		t.Error("expected flips over single turns to be exact")
+	}
+	h.flip(0, 10, 6)
+	if !h.approximate {
+		t.Error("expected a flip over 6 turns to make the history approximate")
+	}
+}
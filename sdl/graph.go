@@ -0,0 +1,131 @@
+package sdl
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const (
+	// The graph panel starts defaultGraphWidth pixels wide and can be resized between minGraphWidth
+	// and half the window.
+	defaultGraphWidth = 300
+	minGraphWidth     = 160
+	graphPadding      = 8
+	graphScale        = 2
+	// graphResizeStep is how far [ and ] resize the panel.
+	graphResizeStep = 40
+)
+
+var (
+	graphBackground = colour{0x20, 0x20, 0x20}
+	populationLine  = colour{0xFF, 0xFF, 0xFF}
+	bornLine        = colour{0x40, 0xE0, 0x40}
+	diedLine        = colour{0xFF, 0x50, 0x40}
+)
+
+// SetStatistics sets the turns plotted by the graph panel, oldest first.
+func (w *Window) SetStatistics(stats []gol.Statistics) {
+	w.statistics = stats
+}
+
+// ToggleGraph shows or hides the graph panel on the right of the window. The world is drawn beside it.
+func (w *Window) ToggleGraph() {
+	w.showGraph = !w.showGraph
+	w.layout()
+}
+
+// ResizeGraph widens the graph panel by dx pixels, or narrows it if dx is negative.
+func (w *Window) ResizeGraph(dx int32) {
+	w.graphWidth += dx
+	w.layout()
+}
+
+// layout shares the window between the world and the graph panel.
+func (w *Window) layout() {
+	if w.graphWidth > w.windowWidth/2 {
+		w.graphWidth = w.windowWidth / 2
+	}
+	if w.graphWidth < minGraphWidth {
+		w.graphWidth = minGraphWidth
+	}
+	worldWidth := w.windowWidth
+	if w.showGraph {
+		worldWidth -= w.graphWidth
+	}
+	w.view.resize(worldWidth, w.windowHeight)
+}
+
+// renderGraph plots population in the top half of the panel and births and deaths in the bottom half.
+func (w *Window) renderGraph() {
+	panel := sdl.Rect{X: w.windowWidth - w.graphWidth, Y: 0, W: w.graphWidth, H: w.windowHeight}
+	w.setDrawColour(graphBackground)
+	util.Check(w.renderer.FillRect(&panel))
+
+	turns := w.statistics
+	label := int32(lineAdvance * graphScale)
+	top := sdl.Rect{X: panel.X + graphPadding, Y: graphPadding + label, W: panel.W - 2*graphPadding}
+	top.H = panel.H/2 - top.Y - graphPadding
+	bottom := top
+	bottom.Y = panel.H/2 + graphPadding + label
+
+	var alive, born, died string
+	maxAlive, maxChanged := 1, 1
+	for _, turn := range turns {
+		maxAlive = maxInt(maxAlive, turn.Alive)
+		maxChanged = maxInt(maxChanged, maxInt(turn.Born, turn.Died))
+	}
+	if len(turns) > 0 {
+		last := turns[len(turns)-1]
+		alive = fmt.Sprintf("Population %v", last.Alive)
+		born = fmt.Sprintf("Born %v", last.Born)
+		died = fmt.Sprintf("Died %v", last.Died)
+	} else {
+		alive = "No statistics"
+	}
+
+	w.drawText(alive, top.X, top.Y-label, populationLine)
+	w.drawText(born, bottom.X, bottom.Y-label, bornLine)
+	w.drawText(died, bottom.X+textWidth(born+"  ", graphScale), bottom.Y-label, diedLine)
+	w.plot(turns, func(s gol.Statistics) int { return s.Alive }, maxAlive, top, populationLine)
+	w.plot(turns, func(s gol.Statistics) int { return s.Born }, maxChanged, bottom, bornLine)
+	w.plot(turns, func(s gol.Statistics) int { return s.Died }, maxChanged, bottom, diedLine)
+	w.setDrawColour(black)
+}
+
+// plot draws value of each turn as a line across area, with max at the top of it.
+func (w *Window) plot(turns []gol.Statistics, value func(gol.Statistics) int, max int, area sdl.Rect, c colour) {
+	if len(turns) < 2 || area.W < 2 || area.H < 2 {
+		return
+	}
+	points := make([]sdl.Point, len(turns))
+	for i, turn := range turns {
+		points[i] = sdl.Point{
+			X: area.X + int32(i)*(area.W-1)/int32(len(turns)-1),
+			Y: area.Y + area.H - 1 - int32(int64(value(turn))*int64(area.H-1)/int64(max)),
+		}
+	}
+	w.setDrawColour(c)
+	util.Check(w.renderer.DrawLines(points))
+}
+
+func (w *Window) drawText(text string, x, y int32, c colour) {
+	if text == "" {
+		return
+	}
+	w.setDrawColour(c)
+	util.Check(w.renderer.FillRects(textRects(text, x, y, graphScale)))
+}
+
+func (w *Window) setDrawColour(c colour) {
+	util.Check(w.renderer.SetDrawColor(c.r, c.g, c.b, 0xFF))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,130 @@
+package sdl
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Zoom levels are powers of two, so every cell is drawn as a whole number of pixels.
+// Level 0 draws one pixel per cell, level n draws 2^n x 2^n pixels per cell,
+// and level -n draws one pixel for every 2^n x 2^n cells.
+const (
+	minZoomLevel = -6
+	maxZoomLevel = 6
+)
+
+// viewport is the part of the world that is shown in the window.
+type viewport struct {
+	worldWidth, worldHeight   int32
+	windowWidth, windowHeight int32
+	level                     int
+	// x and y are the world coordinates shown at the top left corner of the window.
+	// They are kept fractional so that slow drags still move a zoomed out view.
+	x, y float64
+}
+
+func newViewport(worldWidth, worldHeight, windowWidth, windowHeight int32) *viewport {
+	v := &viewport{worldWidth: worldWidth, worldHeight: worldHeight}
+	v.resize(windowWidth, windowHeight)
+	v.fit()
+	return v
+}
+
+// pixelsPerCell returns the number of pixels across one cell, which is less than one when zoomed out.
+func (v *viewport) pixelsPerCell() float64 {
+	if v.level >= 0 {
+		return float64(int32(1) << uint(v.level))
+	}
+	return 1 / float64(int32(1)<<uint(-v.level))
+}
+
+// fit picks the largest zoom level that shows the whole world and centres it.
+func (v *viewport) fit() {
+	v.level = maxZoomLevel
+	for v.level > minZoomLevel &&
+		(float64(v.worldWidth)*v.pixelsPerCell() > float64(v.windowWidth) ||
+			float64(v.worldHeight)*v.pixelsPerCell() > float64(v.windowHeight)) {
+		v.level--
+	}
+	v.clamp()
+}
+
+// resize records a new window size.
+func (v *viewport) resize(windowWidth, windowHeight int32) {
+	v.windowWidth, v.windowHeight = windowWidth, windowHeight
+	v.clamp()
+}
+
+// zoom changes the zoom level by steps, keeping the cell under the window position (x, y) in place.
+func (v *viewport) zoom(steps int, x, y int32) {
+	cellX, cellY := v.cellAt(x, y)
+	v.level += steps
+	if v.level < minZoomLevel {
+		v.level = minZoomLevel
+	}
+	if v.level > maxZoomLevel {
+		v.level = maxZoomLevel
+	}
+	v.x = cellX - float64(x)/v.pixelsPerCell()
+	v.y = cellY - float64(y)/v.pixelsPerCell()
+	v.clamp()
+}
+
+// pan moves the world by dx, dy pixels, as when it is dragged.
+func (v *viewport) pan(dx, dy int32) {
+	v.x -= float64(dx) / v.pixelsPerCell()
+	v.y -= float64(dy) / v.pixelsPerCell()
+	v.clamp()
+}
+
+// cellAt returns the world coordinates under the window position (x, y), which may be outside the world.
+func (v *viewport) cellAt(x, y int32) (float64, float64) {
+	return v.x + float64(x)/v.pixelsPerCell(), v.y + float64(y)/v.pixelsPerCell()
+}
+
+// clamp centres the world along any axis where it is smaller than the window,
+// and otherwise stops it being dragged away from the edges of the window.
+func (v *viewport) clamp() {
+	v.x = clampAxis(v.x, v.worldWidth, v.windowWidth, v.pixelsPerCell())
+	v.y = clampAxis(v.y, v.worldHeight, v.windowHeight, v.pixelsPerCell())
+}
+
+func clampAxis(origin float64, world, window int32, pixelsPerCell float64) float64 {
+	visible := float64(window) / pixelsPerCell
+	if visible >= float64(world) {
+		return (float64(world) - visible) / 2
+	}
+	if origin < 0 {
+		return 0
+	}
+	if origin > float64(world)-visible {
+		return float64(world) - visible
+	}
+	return origin
+}
+
+// visible returns the cells that can be seen and where in the window they are drawn.
+func (v *viewport) visible() (src, dst sdl.Rect) {
+	srcX, dstX, srcW, dstW := visibleAxis(v.x, v.worldWidth, v.windowWidth, v.pixelsPerCell())
+	srcY, dstY, srcH, dstH := visibleAxis(v.y, v.worldHeight, v.windowHeight, v.pixelsPerCell())
+	return sdl.Rect{X: srcX, Y: srcY, W: srcW, H: srcH}, sdl.Rect{X: dstX, Y: dstY, W: dstW, H: dstH}
+}
+
+// visibleAxis works out the visible range of cells along one axis, rounded out to whole cells,
+// and the range of pixels they cover.
+func visibleAxis(origin float64, world, window int32, pixelsPerCell float64) (src, dst, srcSize, dstSize int32) {
+	first := int32(origin)
+	if origin < 0 {
+		first = 0
+	}
+	last := int32(origin+float64(window)/pixelsPerCell) + 1
+	if last > world {
+		last = world
+	}
+	if last <= first {
+		return 0, 0, 0, 0
+	}
+	dst = int32((float64(first) - origin) * pixelsPerCell)
+	dstSize = int32(float64(last-first) * pixelsPerCell)
+	if dstSize < 1 {
+		dstSize = 1
+	}
+	return first, dst, last - first, dstSize
+}
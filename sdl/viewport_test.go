@@ -0,0 +1,102 @@
+package sdl
+
+import "testing"
+
+// TestViewportFit checks that fit picks the largest zoom level that shows the whole world, and centres it.
+func TestViewportFit(t *testing.T) {
+	for _, test := range []struct {
+		name                      string
+		worldWidth, worldHeight   int32
+		windowWidth, windowHeight int32
+		level                     int
+		x, y                      float64
+	}{
+		{"exact", 512, 512, 512, 512, 0, 0, 0},
+		{"zoomed in", 16, 16, 512, 512, 5, 0, 0},
+		{"zoomed out", 4096, 4096, 512, 512, -3, 0, 0},
+		{"wide", 64, 32, 512, 512, 3, 0, -16},
+		{"largest zoom", 2, 2, 512, 512, maxZoomLevel, -3, -3},
+		{"smallest zoom", 1 << 20, 1 << 20, 512, 512, minZoomLevel, 0, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v := newViewport(test.worldWidth, test.worldHeight, test.windowWidth, test.windowHeight)
+			if v.level != test.level || v.x != test.x || v.y != test.y {
+				t.Errorf("expected level %v at (%v, %v), got level %v at (%v, %v)", test.level, test.x, test.y, v.level, v.x, v.y)
+			}
+		})
+	}
+}
+
+// TestViewportZoom checks that zooming keeps the cell under the pointer in place, within the zoom limits.
+func TestViewportZoom(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		steps  int
+		px, py int32
+		level  int
+		x, y   float64
+	}{
+		{"in at centre", 1, 256, 256, 1, 128, 128},
+		{"in at top left", 1, 0, 0, 1, 0, 0},
+		{"in at bottom right", 1, 512, 512, 1, 256, 256},
+		{"in at point", 2, 100, 300, 2, 75, 225},
+		{"out", -1, 100, 100, -1, -256, -256},
+		{"past largest", 20, 256, 256, maxZoomLevel, 252, 252},
+		{"past smallest", -20, 256, 256, minZoomLevel, -16128, -16128},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v := newViewport(512, 512, 512, 512)
+			v.zoom(test.steps, test.px, test.py)
+			if v.level != test.level || v.x != test.x || v.y != test.y {
+				t.Errorf("expected level %v at (%v, %v), got level %v at (%v, %v)", test.level, test.x, test.y, v.level, v.x, v.y)
+			}
+		})
+	}
+}
+
+// TestViewportPan checks that panning moves the view by whole pixels at any zoom level,
+// and stops at the edges of the world.
+func TestViewportPan(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		worldSize int32
+		level     int
+		dx, dy    int32
+		x, y      float64
+	}{
+		{"drag", 512, 0, -100, -50, 100, 50},
+		{"past top left", 512, 0, 100, 100, 0, 0},
+		{"past bottom right", 512, 0, -1000, -1000, 256, 256},
+		{"zoomed in", 512, 1, -100, 0, 50, 0},
+		{"zoomed in past bottom right", 512, 1, -1000, -1000, 384, 384},
+		{"zoomed out", 2048, -2, -100, -10, 400, 40},
+		{"smaller than window", 64, 0, -100, 100, -96, -96},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v := &viewport{worldWidth: test.worldSize, worldHeight: test.worldSize, level: test.level}
+			v.resize(256, 256)
+			v.pan(test.dx, test.dy)
+			if v.x != test.x || v.y != test.y {
+				t.Errorf("expected (%v, %v), got (%v, %v)", test.x, test.y, v.x, v.y)
+			}
+		})
+	}
+}
+
+// TestViewportCellAt checks the cell under a window position at zoom levels above, at and below 1.
+func TestViewportCellAt(t *testing.T) {
+	for _, test := range []struct {
+		level int
+		cellX float64
+		cellY float64
+	}{
+		{2, 12, 23},
+		{0, 18, 32},
+		{-2, 42, 68},
+	} {
+		v := &viewport{level: test.level, x: 10, y: 20}
+		if x, y := v.cellAt(8, 12); x != test.cellX || y != test.cellY {
+			t.Errorf("level %v: expected (%v, %v), got (%v, %v)", test.level, test.cellX, test.cellY, x, y)
+		}
+	}
+}
@@ -4,31 +4,63 @@ import (
 	"fmt"
 
 	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
+// Width and Height are the size of the world. The window itself starts at a size that shows the whole world
+// at a whole number of pixels per cell, and can be resized, zoomed and panned.
 type Window struct {
 	Width, Height int32
 	window        *sdl.Window
 	renderer      *sdl.Renderer
 	texture       *sdl.Texture
 	pixels        []byte
+	view          *viewport
+	history       *cellHistory
+	mode          ColourMode
+	turn          int
+	overlay       []string
+	// windowWidth and windowHeight are the size of the window, which the world shares with the graph panel.
+	windowWidth, windowHeight int32
+	statistics                []gol.Statistics
+	graphWidth                int32
+	showGraph                 bool
 }
 
+// maxWindowFraction is how much of the screen a new window may cover.
+const maxWindowFraction = 0.8
+
 func filterEvent(e sdl.Event, userdata interface{}) bool {
-	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
+	switch e.GetType() {
+	case sdl.KEYDOWN, sdl.QUIT, sdl.MOUSEWHEEL, sdl.MOUSEBUTTONDOWN, sdl.MOUSEBUTTONUP, sdl.MOUSEMOTION, sdl.WINDOWEVENT:
+		return true
+	}
+	return false
 }
 
 func NewWindow(width, height int32) *Window {
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	util.Check(err)
-	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
+
+	// Small worlds are scaled up and large ones scaled down so the window fits comfortably on the screen.
+	screenWidth, screenHeight := int32(1024), int32(768)
+	if bounds, err := sdl.GetDisplayBounds(0); err == nil {
+		screenWidth = int32(float64(bounds.W) * maxWindowFraction)
+		screenHeight = int32(float64(bounds.H) * maxWindowFraction)
+	}
+	view := newViewport(width, height, screenWidth, screenHeight)
+	windowWidth := int32(float64(width) * view.pixelsPerCell())
+	windowHeight := int32(float64(height) * view.pixelsPerCell())
+	view.resize(windowWidth, windowHeight)
+
+	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED,
+		windowWidth, windowHeight, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	util.Check(err)
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
 	util.Check(err)
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
-	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
+	// Zoomed in cells are drawn as sharp squares.
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest")
 	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
 	util.Check(err)
 
@@ -40,6 +72,16 @@ func NewWindow(width, height int32) *Window {
 		renderer,
 		texture,
 		make([]byte, width*height*4),
+		view,
+		newCellHistory(int(width * height)),
+		Mono,
+		0,
+		nil,
+		windowWidth,
+		windowHeight,
+		nil,
+		defaultGraphWidth,
+		false,
 	}
 }
 
@@ -53,52 +95,175 @@ func (w *Window) Destroy() {
 	sdl.Quit()
 }
 
+// RenderFrame draws the part of the world inside the viewport. Only the visible cells are copied to the texture.
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, w.pixels, int(w.Width*4))
-	util.Check(err)
-	err = w.renderer.Clear()
-	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
+	src, dst := w.view.visible()
+	err := w.renderer.Clear()
 	util.Check(err)
+	if src.W > 0 && src.H > 0 {
+		if w.mode != Mono {
+			// Colours change with the turn even for cells that did not flip.
+			for y := int(src.Y); y < int(src.Y+src.H); y++ {
+				for x := int(src.X); x < int(src.X+src.W); x++ {
+					w.paint(y*int(w.Width) + x)
+				}
+			}
+		}
+		offset := 4 * (int(src.Y)*int(w.Width) + int(src.X))
+		err = w.texture.Update(&src, w.pixels[offset:], int(w.Width*4))
+		util.Check(err)
+		err = w.renderer.Copy(w.texture, &src, &dst)
+		util.Check(err)
+	}
+	if w.showGraph {
+		w.renderGraph()
+	}
+	if len(w.overlay) > 0 {
+		w.renderOverlay()
+	}
 	w.renderer.Present()
 }
 
+// Overlay text is drawn at overlayScale screen pixels per font pixel, on a translucent box.
+const (
+	overlayScale   = 2
+	overlayPadding = 6
+)
+
+// SetOverlay sets the lines of text drawn over the top left corner of the world. nil removes the overlay.
+func (w *Window) SetOverlay(lines []string) {
+	w.overlay = lines
+}
+
+func (w *Window) renderOverlay() {
+	var width int32
+	var rects []sdl.Rect
+	for i, line := range w.overlay {
+		if lineWidth := textWidth(line, overlayScale); lineWidth > width {
+			width = lineWidth
+		}
+		y := overlayPadding + int32(i)*lineAdvance*overlayScale
+		rects = append(rects, textRects(line, overlayPadding, y, overlayScale)...)
+	}
+	height := int32(len(w.overlay))*lineAdvance*overlayScale - (lineAdvance-glyphHeight)*overlayScale
+	box := sdl.Rect{X: 0, Y: 0, W: width + 2*overlayPadding, H: height + 2*overlayPadding}
+
+	util.Check(w.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND))
+	util.Check(w.renderer.SetDrawColor(0, 0, 0, 0xA0))
+	util.Check(w.renderer.FillRect(&box))
+	util.Check(w.renderer.SetDrawColor(0xFF, 0xFF, 0xFF, 0xFF))
+	util.Check(w.renderer.FillRects(rects))
+	// Clear uses the draw colour, so it is put back to black.
+	util.Check(w.renderer.SetDrawColor(0, 0, 0, 0xFF))
+	util.Check(w.renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE))
+}
+
+// Zoom zooms in by steps powers of two, or out if steps is negative, around the window position (x, y).
+func (w *Window) Zoom(steps int, x, y int32) {
+	w.view.zoom(steps, x, y)
+}
+
+// Pan drags the world by dx, dy pixels.
+func (w *Window) Pan(dx, dy int32) {
+	w.view.pan(dx, dy)
+}
+
+// Fit zooms to show the whole world.
+func (w *Window) Fit() {
+	w.view.fit()
+}
+
+// CellAt returns the cell under the window position (x, y), or false if there is none.
+func (w *Window) CellAt(x, y int32) (util.Cell, bool) {
+	if x >= w.view.windowWidth {
+		// The position is in the graph panel.
+		return util.Cell{}, false
+	}
+	cellX, cellY := w.view.cellAt(x, y)
+	if cellX < 0 || cellY < 0 || cellX >= float64(w.Width) || cellY >= float64(w.Height) {
+		return util.Cell{}, false
+	}
+	return util.Cell{X: int(cellX), Y: int(cellY)}, true
+}
+
+// Alive reports whether cell is drawn as alive.
+func (w *Window) Alive(cell util.Cell) bool {
+	return w.history.alive[cell.Y*int(w.Width)+cell.X]
+}
+
+// SetTurn sets the turn that the following flips happened in and that colours are worked out for.
+func (w *Window) SetTurn(turn int) {
+	w.turn = turn
+}
+
+// ColourMode returns how cells are coloured.
+func (w *Window) ColourMode() ColourMode {
+	return w.mode
+}
+
+// SetColourMode changes how cells are coloured and recolours every cell.
+func (w *Window) SetColourMode(mode ColourMode) {
+	w.mode = mode
+	for i := range w.history.alive {
+		w.paint(i)
+	}
+}
+
+// paint writes the colour of cell i into the pixel buffer.
+func (w *Window) paint(i int) {
+	c := w.history.colour(i, w.turn, w.mode)
+	w.pixels[4*i+0] = c.b
+	w.pixels[4*i+1] = c.g
+	w.pixels[4*i+2] = c.r
+	w.pixels[4*i+3] = 0xFF
+}
+
+// Resize tells the window it is now width x height pixels.
+func (w *Window) Resize(width, height int32) {
+	w.windowWidth, w.windowHeight = width, height
+	w.layout()
+}
+
+// SetTitle replaces the text in the title bar, for example to show an error.
+func (w *Window) SetTitle(title string) {
+	w.window.SetTitle(title)
+}
+
 func (w *Window) PollEvent() sdl.Event {
 	return sdl.PollEvent()
 }
 
 func (w *Window) SetPixel(x, y int) {
-	width := int(w.Width)
-	w.pixels[4*(y*width+x)+0] = 0xFF
-	w.pixels[4*(y*width+x)+1] = 0xFF
-	w.pixels[4*(y*width+x)+2] = 0xFF
-	w.pixels[4*(y*width+x)+3] = 0xFF
+	i := y*int(w.Width) + x
+	if !w.history.alive[i] {
+		w.history.flip(i, w.turn, 1)
+	}
+	w.paint(i)
 }
 
 func (w *Window) FlipPixel(x, y int) {
-	if x < 0 || y < 0 || x >= int(w.Width) || y >= int(w.Height) {
-		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the window.", x, y))
+	w.FlipCell(util.Cell{X: x, Y: y}, 1)
+}
+
+// FlipCell is like FlipPixel, where turns is how many turns the cell changed over.
+// Cells that did not change by a turn, because the world was loaded or edited, do not count as activity.
+func (w *Window) FlipCell(cell util.Cell, turns int) {
+	if cell.X < 0 || cell.Y < 0 || cell.X >= int(w.Width) || cell.Y >= int(w.Height) {
+		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the window.", cell.X, cell.Y))
 	}
 
-	width := int(w.Width)
-	w.pixels[4*(y*width+x)+0] = ^w.pixels[4*(y*width+x)+0]
-	w.pixels[4*(y*width+x)+1] = ^w.pixels[4*(y*width+x)+1]
-	w.pixels[4*(y*width+x)+2] = ^w.pixels[4*(y*width+x)+2]
-	w.pixels[4*(y*width+x)+3] = ^w.pixels[4*(y*width+x)+3]
+	i := cell.Y*int(w.Width) + cell.X
+	w.history.flip(i, w.turn, turns)
+	w.paint(i)
 }
 
 func (w *Window) CountPixels() int {
-	count := 0
-	for i := 0; i < int(w.Width) * int(w.Height) * 4; i += 4 {
-		if w.pixels[i] == 0xFF {
-			count++
-		}
-	}
-	return count
+	return w.history.population
 }
 
 func (w *Window) ClearPixels() {
 	for i := range w.pixels {
 		w.pixels[i] = 0
 	}
+	w.history = newCellHistory(int(w.Width * w.Height))
 }
@@ -0,0 +1,160 @@
+package sdl
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+	"uk.ac.bris.cs/gameoflife/vis"
+)
+
+// WindowRenderer shows a run in a Window. Besides the keys passed on to the run, f fits the world to the window,
+// m switches to the next ColourMode, h hides or shows the hud, and g hides or shows the graph panel,
+// which [ and ] narrow and widen. The mouse wheel zooms and dragging pans, except that while the Frame is Editable
+// a left click on a dead cell paints alive cells while the button is held, and on an alive cell erases them.
+// Outside Mono the hud names the ColourMode, marked approximate once frames have skipped turns.
+type WindowRenderer struct {
+	w       *Window
+	frame   vis.Frame
+	showHud bool
+
+	painting   bool
+	paintAlive bool
+	lastCell   util.Cell
+}
+
+// NewWindowRenderer opens a window for a width x height world.
+func NewWindowRenderer(width, height int) *WindowRenderer {
+	return &WindowRenderer{w: NewWindow(int32(width), int32(height)), showHud: true}
+}
+
+func (r *WindowRenderer) Flip(turn, turns int, cell util.Cell) {
+	r.w.SetTurn(turn)
+	r.w.FlipCell(cell, turns)
+}
+
+func (r *WindowRenderer) Render(frame vis.Frame) {
+	if frame.Error != r.frame.Error {
+		r.w.SetTitle(fmt.Sprintf("GOL GUI - %v", frame.Error))
+	}
+	if !frame.Editable {
+		r.painting = false
+	}
+	r.frame = frame
+	r.w.SetTurn(frame.Turn)
+	r.draw()
+}
+
+// draw renders the window with the hud and graph panel for the last Frame.
+func (r *WindowRenderer) draw() {
+	if r.showHud {
+		lines := vis.StatusLines(r.frame, r.w.CountPixels())
+		if mode := r.w.ColourMode(); mode != Mono {
+			colours := "Colours " + mode.String()
+			if r.w.history.approximate {
+				// Frames skipped turns, so flips in between were missed.
+				colours += " (approximate)"
+			}
+			lines = append(lines, colours)
+		}
+		r.w.SetOverlay(lines)
+	} else {
+		r.w.SetOverlay(nil)
+	}
+	r.w.SetStatistics(r.frame.Statistics)
+	r.w.RenderFrame()
+}
+
+func (r *WindowRenderer) Poll() vis.Input {
+	for event := r.w.PollEvent(); event != nil; event = r.w.PollEvent() {
+		if input := r.handle(event); input != nil {
+			return input
+		}
+	}
+	return nil
+}
+
+func (r *WindowRenderer) Close() {
+	r.w.Destroy()
+}
+
+// handle carries out anything event does to the window itself, and returns the Input for the run if there is one.
+func (r *WindowRenderer) handle(event sdl.Event) vis.Input {
+	w := r.w
+	switch e := event.(type) {
+	case *sdl.KeyboardEvent:
+		switch e.Keysym.Sym {
+		case sdl.K_p:
+			return vis.Key('p')
+		case sdl.K_s:
+			return vis.Key('s')
+		case sdl.K_q:
+			return vis.Key('q')
+		case sdl.K_k:
+			return vis.Key('k')
+		case sdl.K_c:
+			return vis.Key('c')
+		case sdl.K_n:
+			return vis.Key('n')
+		case sdl.K_u:
+			return vis.Key('u')
+		case sdl.K_PLUS, sdl.K_EQUALS, sdl.K_KP_PLUS:
+			return vis.Key('+')
+		case sdl.K_MINUS, sdl.K_KP_MINUS:
+			return vis.Key('-')
+		case sdl.K_f:
+			w.Fit()
+		case sdl.K_m:
+			w.SetColourMode(w.ColourMode().Next())
+		case sdl.K_h:
+			r.showHud = !r.showHud
+		case sdl.K_g:
+			w.ToggleGraph()
+		case sdl.K_LEFTBRACKET:
+			w.ResizeGraph(-graphResizeStep)
+		case sdl.K_RIGHTBRACKET:
+			w.ResizeGraph(graphResizeStep)
+		default:
+			return nil
+		}
+	case *sdl.MouseWheelEvent:
+		x, y, _ := sdl.GetMouseState()
+		w.Zoom(int(e.Y), x, y)
+	case *sdl.MouseButtonEvent:
+		if e.Button != sdl.BUTTON_LEFT {
+			return nil
+		}
+		r.painting = false
+		if e.Type == sdl.MOUSEBUTTONDOWN && r.frame.Editable {
+			if cell, ok := w.CellAt(e.X, e.Y); ok {
+				r.painting = true
+				r.paintAlive = !w.Alive(cell)
+				r.lastCell = cell
+				return vis.Edit{Cells: []util.Cell{cell}, Alive: r.paintAlive}
+			}
+		}
+		return nil
+	case *sdl.MouseMotionEvent:
+		if r.painting {
+			if cell, ok := w.CellAt(e.X, e.Y); ok && cell != r.lastCell {
+				r.lastCell = cell
+				return vis.Edit{Cells: []util.Cell{cell}, Alive: r.paintAlive}
+			}
+			return nil
+		}
+		if e.State == 0 {
+			return nil
+		}
+		w.Pan(e.XRel, e.YRel)
+	case *sdl.WindowEvent:
+		if e.Event != sdl.WINDOWEVENT_SIZE_CHANGED {
+			return nil
+		}
+		w.Resize(e.Data1, e.Data2)
+	default:
+		return nil
+	}
+	// The view changed, so redraw it.
+	r.draw()
+	return nil
+}
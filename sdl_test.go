@@ -10,6 +10,7 @@ import (
 
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/vis"
 )
 
 var sdlEvents chan gol.Event
@@ -31,98 +32,70 @@ func TestMain(m *testing.M) {
 		}()
 		result <- res
 	}()
-	// sdl.Run(p, sdlEvents, nil)
-	var w *sdl.Window = nil
+	// The recorder counts the cells shown after each turn, and passes everything on to the window if there is one.
+	recorder := vis.NewRecordingRenderer(p.ImageWidth, p.ImageHeight, nil)
 	if !(*noVis) {
-		w = sdl.NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+		recorder.Next = sdl.NewWindowRenderer(p.ImageWidth, p.ImageHeight)
 	}
-
-	board := make([][]byte, p.ImageHeight)
-	for i := 0; i < p.ImageHeight; i++ {
-		board[i] = make([]byte, p.ImageWidth)
-	}
-
-sdlLoop:
-	for {
-		w.PollEvent()
-		select {
-		case event, ok := <-sdlEvents:
-			if !ok {
-				if w != nil {
-					w.Destroy()
-				}
-				break sdlLoop
-			}
-			switch e := event.(type) {
-			case gol.CellFlipped:
-				board[e.Cell.Y][e.Cell.X] = ^board[e.Cell.Y][e.Cell.X]
-				if w != nil {
-					w.FlipPixel(e.Cell.X, e.Cell.Y)
-				}
-			case gol.TurnComplete:
-				if w != nil {
-					w.RenderFrame()
-				}
-				count := 0
-				for y := 0; y < p.ImageHeight; y++ {
-					for x := 0; x < p.ImageWidth; x++ {
-						if board[y][x] == 255 {
-							count++
-						}
-					}
-				}
-				sdlAlive <- count
-			case gol.FinalTurnComplete:
-				if w != nil {
-					w.Destroy()
-				}
-				break sdlLoop
-			default:
-				if len(event.String()) > 0 {
-					fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
-				}
-			}
-		default:
-			break
-		}
+	recorder.OnRender = func(vis.Frame) {
+		sdlAlive <- recorder.Population()
 	}
+	vis.RunRenderer(recorder, sdlEvents, nil)
 	os.Exit(<-result)
 }
 
-// TestSdl tests a 512x512 image for 100 turns using 8 worker threads.
+// TestSdl tests a 512x512 image for 100 turns using 8 worker threads,
+// first with a CellFlipped event per cell and then with the cells of each turn batched into CellsFlipped.
 func TestSdl(t *testing.T) {
 	p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 8}
 	testName := fmt.Sprintf("%dx%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
 	alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
 	t.Run(testName, func(t *testing.T) {
-		turnNum := 0
-		events := make(chan gol.Event)
-		go gol.Run(p, events, nil)
-		time.Sleep(2 * time.Second)
-		final := false
-		for event := range events {
-			switch e := event.(type) {
-			case gol.CellFlipped:
-				sdlEvents <- e
-			case gol.TurnComplete:
-				turnNum++
-				sdlEvents <- e
-				aliveCount := <-sdlAlive
-				if alive[turnNum] != aliveCount {
-					t.Logf("Incorrect number of alive cells displayed on turn %d. Was %d, should be %d.", turnNum, aliveCount, alive[turnNum])
-					time.Sleep(5 * time.Second)
-					sdlEvents <- gol.FinalTurnComplete{}
-					t.FailNow()
-				}
-			case gol.FinalTurnComplete:
-				final = true
-				sdlEvents <- e
+		testSdl(t, p, alive)
+	})
+	p.BatchFlips = true
+	t.Run(testName+"-batched", func(t *testing.T) {
+		testSdl(t, p, alive)
+	})
+}
+
+// testSdl runs p and checks the number of alive cells the visualiser shows after every turn.
+// The visualiser is shared by every subtest, so the final world is cleared from it instead of closing it.
+func testSdl(t *testing.T, p gol.Params, alive map[int]int) {
+	turnNum := 0
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	time.Sleep(2 * time.Second)
+	final := false
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			if p.BatchFlips {
+				t.Errorf("CellFlipped event sent on turn %d with BatchFlips set.", e.CompletedTurns)
+			}
+			sdlEvents <- e
+		case gol.CellsFlipped:
+			if !p.BatchFlips {
+				t.Errorf("CellsFlipped event sent on turn %d without BatchFlips set.", e.CompletedTurns)
 			}
+			sdlEvents <- e
+		case gol.TurnComplete:
+			turnNum++
+			sdlEvents <- e
+			aliveCount := <-sdlAlive
+			if alive[turnNum] != aliveCount && !t.Failed() {
+				t.Errorf("Incorrect number of alive cells displayed on turn %d. Was %d, should be %d.", turnNum, aliveCount, alive[turnNum])
+				time.Sleep(5 * time.Second)
+			}
+		case gol.FinalTurnComplete:
+			final = true
+			sdlEvents <- gol.CellsFlipped{CompletedTurns: e.CompletedTurns, Cells: e.Alive}
+			sdlEvents <- gol.TurnComplete{CompletedTurns: e.CompletedTurns}
+			<-sdlAlive
 		}
+	}
 
-		if !final {
-			sdlEvents <- gol.FinalTurnComplete{}
-			t.Fatal("Simulation finished without sending a FinalTurnComplete event.")
-		}
-	})
+	if !final {
+		t.Fatal("Simulation finished without sending a FinalTurnComplete event.")
+	}
 }
@@ -0,0 +1,89 @@
+package vis
+
+import (
+	"fmt"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// hudSample is how long turns are counted for before the measured turns per second is updated.
+const hudSample = time.Second
+
+// graphTurns is how many of the latest turns of statistics are kept for the graph panel.
+const graphTurns = 500
+
+// hud collects the status of a run from its events, for the Frame passed to Render.
+type hud struct {
+	frame Frame
+
+	sampleTurn int
+	sampleTime time.Time
+	statistics []gol.Statistics
+}
+
+// update records anything from event that a Frame shows. now is when the event was received.
+func (h *hud) update(event gol.Event, now time.Time) {
+	switch e := event.(type) {
+	case gol.TurnComplete:
+		h.frame.Turn = e.CompletedTurns
+		if h.sampleTime.IsZero() {
+			h.sampleTurn, h.sampleTime = e.CompletedTurns, now
+		} else if elapsed := now.Sub(h.sampleTime); elapsed >= hudSample {
+			h.frame.TurnsPerSecond = float64(e.CompletedTurns-h.sampleTurn) / elapsed.Seconds()
+			h.sampleTurn, h.sampleTime = e.CompletedTurns, now
+		}
+	case gol.StateChange:
+		h.frame.Turn = e.CompletedTurns
+		h.frame.State = e.NewState.String()
+		h.frame.Limit = e.TurnsPerSecond
+		h.frame.Editable = e.NewState == gol.Paused
+		if e.NewState == gol.Paused {
+			h.frame.TurnsPerSecond = 0
+			h.sampleTime = time.Time{}
+		}
+	case gol.ImageOutputComplete:
+		h.frame.Snapshot = e.Filename
+	case gol.Error:
+		h.frame.Error = e.String()
+	case gol.TurnStatistics:
+		h.statistics = append(h.statistics, e.Turns...)
+		if len(h.statistics) > 2*graphTurns {
+			h.statistics = append([]gol.Statistics(nil), h.statistics[len(h.statistics)-graphTurns:]...)
+		}
+	}
+}
+
+// current returns the Frame for the events so far.
+func (h *hud) current() Frame {
+	frame := h.frame
+	frame.Statistics = h.statistics
+	if len(frame.Statistics) > graphTurns {
+		frame.Statistics = frame.Statistics[len(frame.Statistics)-graphTurns:]
+	}
+	return frame
+}
+
+// StatusLines returns the status text for frame in a world with alive cells,
+// which the window draws as its hud and the terminal joins into its status line.
+func StatusLines(frame Frame, alive int) []string {
+	tps := fmt.Sprintf("TPS %.1f", frame.TurnsPerSecond)
+	if frame.Limit > 0 {
+		tps += fmt.Sprintf(" (LIMIT %v)", frame.Limit)
+	}
+	snapshot := frame.Snapshot
+	if snapshot == "" {
+		snapshot = "none"
+	}
+	state := frame.State
+	if state == "" {
+		state = "Starting"
+	}
+	return []string{
+		fmt.Sprintf("Turn %v", frame.Turn),
+		fmt.Sprintf("Alive %v", alive),
+		tps,
+		state,
+		"Snapshot " + snapshot,
+	}
+}
@@ -0,0 +1,108 @@
+package vis
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// pollInterval is how long the loop waits for an event before polling the Renderer for input again.
+const pollInterval = 5 * time.Millisecond
+
+// RunRenderer shows the events of gol.RunCommands on r and sends it a Command for each Input,
+// translating keys with gol.Keys. If commands is nil, input is ignored.
+// It returns once the run has finished, reporting whether FinalTurnComplete was received.
+func RunRenderer(r Renderer, events <-chan gol.Event, commands chan<- gol.Command) bool {
+	if commands == nil {
+		return loop(r, events, nil, nil)
+	}
+	keys := gol.NewKeys()
+	return loop(r, events, func(key rune) {
+		if command := keys.Command(key); command != nil {
+			commands <- command
+		}
+	}, func(edit gol.Edit) { commands <- edit })
+}
+
+// RunKeys is like RunRenderer for gol.Run, sending the keys understood by gol.Keys to keyPresses.
+// Edits are ignored. If keyPresses is nil, keys are ignored too.
+func RunKeys(r Renderer, events <-chan gol.Event, keyPresses chan<- rune) bool {
+	var key func(rune)
+	if keyPresses != nil {
+		key = func(k rune) { keyPresses <- k }
+	}
+	return loop(r, events, key, nil)
+}
+
+// loop is shared by RunRenderer and RunKeys. key and edit are nil if keys or edits are ignored.
+func loop(r Renderer, events <-chan gol.Event, key func(rune), edit func(gol.Edit)) bool {
+	defer r.Close()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if logger, ok := r.(Logger); ok {
+		stdout, stderr = logger.Log(), logger.Log()
+	}
+	status := &hud{}
+	render := func() {
+		frame := status.current()
+		frame.Editable = frame.Editable && edit != nil
+		r.Render(frame)
+	}
+
+	for {
+		for input := r.Poll(); input != nil; input = r.Poll() {
+			switch in := input.(type) {
+			case Key:
+				if key != nil {
+					key(rune(in))
+				}
+			case Edit:
+				if edit != nil && status.frame.Editable {
+					edit(gol.Edit{Cells: in.Cells, Alive: in.Alive})
+				}
+			}
+		}
+
+		var event gol.Event
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			event = e
+		case <-ticker.C:
+			continue
+		}
+
+		status.update(event, time.Now())
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			r.Flip(e.CompletedTurns, 1, e.Cell)
+		case gol.CellsFlipped:
+			for _, cell := range e.Cells {
+				r.Flip(e.CompletedTurns, e.Turns, cell)
+			}
+		case gol.TurnComplete:
+			render()
+		case gol.TurnStatistics:
+			// Kept by status for the next Frame.
+		case gol.FinalTurnComplete:
+			return true
+		case gol.Error:
+			fmt.Fprintf(stderr, "Completed Turns %-8v%v\n", e.CompletedTurns, e)
+			render()
+		case gol.StateChange, gol.ImageOutputComplete:
+			render()
+			fmt.Fprintf(stdout, "Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+		default:
+			if len(event.String()) > 0 {
+				fmt.Fprintf(stdout, "Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package vis
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// runEvents runs the loop on r for events, collecting the Commands it sends.
+func runEvents(r Renderer, events ...gol.Event) (bool, []gol.Command) {
+	eventChannel := make(chan gol.Event, len(events))
+	for _, event := range events {
+		eventChannel <- event
+	}
+	close(eventChannel)
+	commands := make(chan gol.Command, 100)
+	completed := RunRenderer(r, eventChannel, commands)
+	close(commands)
+	var sent []gol.Command
+	for command := range commands {
+		sent = append(sent, command)
+	}
+	return completed, sent
+}
+
+// TestRunRendererFrames checks that flips are applied before each turn is rendered.
+func TestRunRendererFrames(t *testing.T) {
+	r := NewRecordingRenderer(4, 4, nil)
+	var populations []int
+	r.OnRender = func(Frame) { populations = append(populations, r.Population()) }
+
+	completed, _ := runEvents(r,
+		gol.CellsFlipped{CompletedTurns: 0, Cells: []util.Cell{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}},
+		gol.StateChange{CompletedTurns: 0, NewState: gol.Executing},
+		gol.CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 1, Y: 0}},
+		gol.CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 1, Y: 2}},
+		gol.CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 0, Y: 1}},
+		gol.TurnComplete{CompletedTurns: 1},
+		gol.FinalTurnComplete{CompletedTurns: 1},
+	)
+
+	if !completed || !r.Closed {
+		t.Errorf("expected a completed run to close the renderer, got completed %v and closed %v", completed, r.Closed)
+	}
+	if expected := []int{3, 2}; !reflect.DeepEqual(populations, expected) {
+		t.Errorf("expected populations %v at each frame, got %v", expected, populations)
+	}
+	if !r.Alive(0, 1) || !r.Alive(1, 1) || r.Alive(1, 0) {
+		t.Error("expected the last flips to leave cells (0, 1) and (1, 1) alive")
+	}
+	if last := r.Frames[len(r.Frames)-1]; last.Turn != 1 || last.State != "Executing" {
+		t.Errorf("expected the last frame to show turn 1 executing, got %+v", last)
+	}
+}
+
+// TestRunRendererInput checks that keys become Commands and that edits are only sent while paused.
+func TestRunRendererInput(t *testing.T) {
+	r := NewRecordingRenderer(4, 4, nil)
+	edit := Edit{Cells: []util.Cell{{X: 2, Y: 3}}, Alive: true}
+	r.Inputs = []Input{Key('p'), Key('x'), edit}
+	r.OnRender = func(frame Frame) {
+		if frame.Editable {
+			r.Inputs = append(r.Inputs, edit)
+		}
+	}
+
+	completed, commands := runEvents(r,
+		gol.StateChange{CompletedTurns: 0, NewState: gol.Executing},
+		gol.StateChange{CompletedTurns: 0, NewState: gol.Paused},
+	)
+
+	if completed {
+		t.Error("expected a run without FinalTurnComplete to be reported as incomplete")
+	}
+	expected := []gol.Command{gol.Pause{}, gol.Edit{Cells: edit.Cells, Alive: true}}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands %#v, got %#v", expected, commands)
+	}
+}
+
+// TestHudTurnsPerSecond checks that the hud measures turns per second over each sample.
+func TestHudTurnsPerSecond(t *testing.T) {
+	h := &hud{}
+	start := time.Unix(0, 0)
+	h.update(gol.StateChange{CompletedTurns: 0, NewState: gol.Throttled, TurnsPerSecond: 50}, start)
+	h.update(gol.TurnComplete{CompletedTurns: 1}, start)
+	h.update(gol.TurnComplete{CompletedTurns: 51}, start.Add(hudSample/2))
+	h.update(gol.TurnComplete{CompletedTurns: 101}, start.Add(2*hudSample))
+
+	frame := h.current()
+	if frame.TurnsPerSecond != 50 || frame.Limit != 50 || frame.Turn != 101 {
+		t.Errorf("expected turn 101 at 50 turns per second with a limit of 50, got %+v", frame)
+	}
+	h.update(gol.StateChange{CompletedTurns: 101, NewState: gol.Paused}, start.Add(3*hudSample))
+	if frame := h.current(); frame.TurnsPerSecond != 0 || !frame.Editable {
+		t.Errorf("expected an editable frame with no turns per second once paused, got %+v", frame)
+	}
+}
+
+// loggingRenderer is a RecordingRenderer that collects the messages of the loop.
+type loggingRenderer struct {
+	*RecordingRenderer
+	log bytes.Buffer
+}
+
+func (r *loggingRenderer) Log() io.Writer {
+	return &r.log
+}
+
+// TestRunRendererLog checks that a Logger is sent the messages that would otherwise be printed.
+func TestRunRendererLog(t *testing.T) {
+	r := &loggingRenderer{RecordingRenderer: NewRecordingRenderer(4, 4, nil)}
+	runEvents(r,
+		gol.StateChange{CompletedTurns: 0, NewState: gol.Executing},
+		gol.Error{CompletedTurns: 2, Err: errors.New("disk full")},
+	)
+	if log := r.log.String(); !strings.Contains(log, "Executing") || !strings.Contains(log, "disk full") {
+		t.Errorf("expected the state change and error in the log, got %q", log)
+	}
+}
@@ -0,0 +1,148 @@
+package vis
+
+import (
+	"fmt"
+	"io"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Renderer shows a run of the Game of Life. RunRenderer and RunKeys share one loop that sends a Renderer
+// the cells that flip, asks it to Render after each turn, and passes the Input it polls back to the run.
+type Renderer interface {
+	// Flip changes the state of cell in turn. The change is shown by the next Render.
+	// turns is how many turns it changed over, like gol.CellsFlipped.Turns: more than 1 when frames skip turns,
+	// and 0 when the world was loaded or edited.
+	Flip(turn, turns int, cell util.Cell)
+	// Render shows the world as it is now, along with frame.
+	Render(frame Frame)
+	// Poll returns the next Input waiting, or nil if there is none. It must not block.
+	Poll() Input
+	// Close is called once, when the run has finished.
+	Close()
+}
+
+// Logger is implemented by Renderers that would be spoilt by messages printed during the run, such as a terminal
+// that owns stdout. The loop writes its messages to Log instead of stdout and stderr, and gol.Params.Log can be set to it too.
+type Logger interface {
+	Log() io.Writer
+}
+
+// Frame is the status of the run at a Render.
+type Frame struct {
+	Turn int
+	// State is the latest state of the run, or empty before the first StateChange.
+	State string
+	// TurnsPerSecond is the measured rate of turns, and Limit is the speed limit set by SetSpeed.
+	TurnsPerSecond float64
+	Limit          float64
+	// Snapshot is the name of the last image written, and Error is the last error reported.
+	Snapshot string
+	Error    string
+	// Editable is set while the run is paused and Edit inputs will be carried out.
+	Editable bool
+	// Statistics are the latest TurnStatistics, oldest first.
+	Statistics []gol.Statistics
+}
+
+// Input is a Key or an Edit, returned by Renderer.Poll.
+type Input interface {
+	input()
+}
+
+// Key is a key pressed to control the run, one of those understood by gol.Keys.
+type Key rune
+
+// Edit makes Cells alive, or dead if Alive is false. It is ignored unless the last Frame was Editable.
+type Edit struct {
+	Cells []util.Cell
+	Alive bool
+}
+
+func (Key) input()  {}
+func (Edit) input() {}
+
+// NullRenderer shows nothing and has no input, for running without a display.
+type NullRenderer struct{}
+
+func (NullRenderer) Flip(turn, turns int, cell util.Cell) {}
+func (NullRenderer) Render(frame Frame)                   {}
+func (NullRenderer) Poll() Input                          { return nil }
+func (NullRenderer) Close()                               {}
+
+// RecordingRenderer remembers the world and every Frame it is sent, so tests can check what a run showed.
+// Poll returns Inputs one at a time. If Next is set, every call is passed on to it too,
+// and Poll returns the input of Next once Inputs is empty, so a run can be recorded while it is shown.
+type RecordingRenderer struct {
+	Width, Height int
+	Frames        []Frame
+	Inputs        []Input
+	Closed        bool
+	Next          Renderer
+	// OnRender is called after each Render if it is set.
+	OnRender func(frame Frame)
+
+	alive      []bool
+	population int
+}
+
+// NewRecordingRenderer returns a RecordingRenderer for a width x height world with every cell dead.
+func NewRecordingRenderer(width, height int, next Renderer) *RecordingRenderer {
+	return &RecordingRenderer{Width: width, Height: height, Next: next, alive: make([]bool, width*height)}
+}
+
+func (r *RecordingRenderer) Flip(turn, turns int, cell util.Cell) {
+	if cell.X < 0 || cell.Y < 0 || cell.X >= r.Width || cell.Y >= r.Height {
+		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the world.", cell.X, cell.Y))
+	}
+	i := cell.Y*r.Width + cell.X
+	r.alive[i] = !r.alive[i]
+	if r.alive[i] {
+		r.population++
+	} else {
+		r.population--
+	}
+	if r.Next != nil {
+		r.Next.Flip(turn, turns, cell)
+	}
+}
+
+func (r *RecordingRenderer) Render(frame Frame) {
+	r.Frames = append(r.Frames, frame)
+	if r.Next != nil {
+		r.Next.Render(frame)
+	}
+	if r.OnRender != nil {
+		r.OnRender(frame)
+	}
+}
+
+func (r *RecordingRenderer) Poll() Input {
+	if len(r.Inputs) > 0 {
+		input := r.Inputs[0]
+		r.Inputs = r.Inputs[1:]
+		return input
+	}
+	if r.Next != nil {
+		return r.Next.Poll()
+	}
+	return nil
+}
+
+func (r *RecordingRenderer) Close() {
+	r.Closed = true
+	if r.Next != nil {
+		r.Next.Close()
+	}
+}
+
+// Alive reports whether the cell at (x, y) has been flipped alive.
+func (r *RecordingRenderer) Alive(x, y int) bool {
+	return r.alive[y*r.Width+x]
+}
+
+// Population returns the number of alive cells.
+func (r *RecordingRenderer) Population() int {
+	return r.population
+}
@@ -0,0 +1,218 @@
+package vis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Each character of the terminal shows a 2x4 block of cells as a braille pattern, starting from U+2800.
+// brailleDots gives the bit of each cell in the block, indexed by [y][x].
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// densityColours are the ANSI foreground colours of blocks with 1-2, 3-4, 5-6 and 7-8 alive cells,
+// so a character only changes colour when one of its cells flips.
+var densityColours = [4]int{34, 36, 32, 33}
+
+// terminalKeys are the keys read from the terminal that are passed on to the run. Ctrl-C quits.
+const terminalKeys = "pscqknu+-"
+
+// TerminalRenderer draws a run in a terminal instead of a window, for watching over SSH.
+// Each character packs a 2x4 block of cells into a braille pattern coloured by how many are alive,
+// and only the characters whose cells flipped are redrawn. The bottom line shows the status of the run.
+// Worlds larger than the terminal are cropped to its top left corner.
+type TerminalRenderer struct {
+	width, height int
+	// cols x rows characters show the world, and the status may be screenCols wide.
+	cols, rows int
+	screenCols int
+	alive      []bool
+	population int
+	// dirty marks the characters with a cell that flipped since the last Render, listed in changed.
+	dirty   []bool
+	changed []int
+	frame   Frame
+
+	out     *bufio.Writer
+	keys    <-chan byte
+	restore func()
+}
+
+// NewTerminalRenderer puts the terminal on stdin into raw mode and draws a width x height world on stdout.
+// Nothing else should write to stdout or stderr until Close, or it will scroll the picture,
+// so the run's messages should be sent to Log.
+func NewTerminalRenderer(width, height int) (*TerminalRenderer, error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("terminal visualiser needs a terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	restore := func() {
+		stty(strings.TrimSpace(state))
+	}
+	rows, cols := 24, 80
+	if size, err := stty("size"); err == nil {
+		fmt.Sscan(size, &rows, &cols)
+	}
+
+	r := newTerminalRenderer(width, height, cols, rows, os.Stdout, nil)
+	// Switch to the alternate screen and hide the cursor.
+	fmt.Fprint(r.out, "\x1b[?1049h\x1b[?25l\x1b[2J")
+	if err := r.out.Flush(); err != nil {
+		restore()
+		return nil, err
+	}
+
+	keys := make(chan byte, 16)
+	go readKeys(os.Stdin, keys)
+	r.keys = keys
+	r.restore = restore
+	return r, nil
+}
+
+func newTerminalRenderer(width, height, cols, rows int, out io.Writer, keys <-chan byte) *TerminalRenderer {
+	r := &TerminalRenderer{
+		width:      width,
+		height:     height,
+		cols:       (width + 1) / 2,
+		rows:       (height + 3) / 4,
+		screenCols: cols,
+		alive:      make([]bool, width*height),
+		out:        bufio.NewWriter(out),
+		keys:       keys,
+	}
+	// The last line is kept for the status.
+	if r.cols > cols {
+		r.cols = cols
+	}
+	if r.rows > rows-1 {
+		r.rows = rows - 1
+	}
+	r.dirty = make([]bool, r.cols*r.rows)
+	return r
+}
+
+// stty runs stty on the terminal and returns what it printed.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Log discards the run's messages, which are shown in the status line instead.
+func (r *TerminalRenderer) Log() io.Writer {
+	return ioutil.Discard
+}
+
+// readKeys sends every byte read from in to keys until in fails.
+func readKeys(in io.Reader, keys chan<- byte) {
+	buffer := make([]byte, 1)
+	for {
+		if _, err := in.Read(buffer); err != nil {
+			return
+		}
+		keys <- buffer[0]
+	}
+}
+
+func (r *TerminalRenderer) Flip(turn, turns int, cell util.Cell) {
+	if cell.X < 0 || cell.Y < 0 || cell.X >= r.width || cell.Y >= r.height {
+		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the world.", cell.X, cell.Y))
+	}
+	i := cell.Y*r.width + cell.X
+	r.alive[i] = !r.alive[i]
+	if r.alive[i] {
+		r.population++
+	} else {
+		r.population--
+	}
+
+	col, row := cell.X/2, cell.Y/4
+	if col < r.cols && row < r.rows && !r.dirty[row*r.cols+col] {
+		r.dirty[row*r.cols+col] = true
+		r.changed = append(r.changed, row*r.cols+col)
+	}
+}
+
+func (r *TerminalRenderer) Render(frame Frame) {
+	r.frame = frame
+	for _, i := range r.changed {
+		r.dirty[i] = false
+		col, row := i%r.cols, i/r.cols
+		glyph, count := r.block(col, row)
+		colour := 0
+		if count > 0 {
+			colour = densityColours[(count-1)/2]
+		}
+		// Cursor positions count from 1.
+		fmt.Fprintf(r.out, "\x1b[%d;%dH\x1b[%dm%c", row+1, col+1, colour, glyph)
+	}
+	r.changed = r.changed[:0]
+
+	status := strings.Join(StatusLines(frame, r.population), "  ")
+	if frame.Error != "" {
+		status += "  " + frame.Error
+	}
+	if runes := []rune(status); len(runes) > r.screenCols {
+		status = string(runes[:r.screenCols])
+	}
+	fmt.Fprintf(r.out, "\x1b[0m\x1b[%d;1H\x1b[2K%s", r.rows+1, status)
+	r.out.Flush()
+}
+
+// block returns the braille character for the cells shown at (col, row) and how many of them are alive.
+func (r *TerminalRenderer) block(col, row int) (rune, int) {
+	glyph, count := rune(0x2800), 0
+	for dy := 0; dy < 4; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			x, y := 2*col+dx, 4*row+dy
+			if x < r.width && y < r.height && r.alive[y*r.width+x] {
+				glyph |= brailleDots[dy][dx]
+				count++
+			}
+		}
+	}
+	return glyph, count
+}
+
+func (r *TerminalRenderer) Poll() Input {
+	for {
+		select {
+		case key := <-r.keys:
+			if key == 3 {
+				return Key('q')
+			}
+			if strings.IndexByte(terminalKeys, key) >= 0 {
+				return Key(key)
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// Close puts the terminal back as it was and prints the last error, which was hidden while drawing.
+func (r *TerminalRenderer) Close() {
+	fmt.Fprint(r.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+	r.out.Flush()
+	if r.restore != nil {
+		r.restore()
+	}
+	if r.frame.Error != "" {
+		fmt.Fprintln(os.Stderr, r.frame.Error)
+	}
+}
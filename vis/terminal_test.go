@@ -0,0 +1,60 @@
+package vis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestTerminalRenderer checks that cells are packed into braille characters and only changed characters are redrawn.
+func TestTerminalRenderer(t *testing.T) {
+	var out bytes.Buffer
+	keys := make(chan byte, 4)
+	r := newTerminalRenderer(6, 8, 80, 24, &out, keys)
+
+	// The top left and bottom right dots of the first character, and the top left dot of the last one.
+	for _, cell := range []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 3}, {X: 4, Y: 4}} {
+		r.Flip(1, 1, cell)
+	}
+	r.Render(Frame{Turn: 1})
+	drawn := out.String()
+	for _, expected := range []string{"\x1b[1;1H\x1b[34m⢁", "\x1b[2;3H\x1b[34m⠁", "Turn 1  Alive 3"} {
+		if !strings.Contains(drawn, expected) {
+			t.Errorf("expected %q in %q", expected, drawn)
+		}
+	}
+
+	out.Reset()
+	r.Render(Frame{Turn: 2})
+	if strings.Contains(out.String(), "\x1b[1;1H") {
+		t.Errorf("expected nothing to be redrawn without flips, got %q", out.String())
+	}
+
+	keys <- 'x'
+	keys <- 'p'
+	keys <- 3
+	if first, second := r.Poll(), r.Poll(); first != Key('p') || second != Key('q') || r.Poll() != nil {
+		t.Errorf("expected p then q from Ctrl-C, got %v and %v", first, second)
+	}
+}
+
+// TestTerminalStatusWidth checks that a long status line is cut to the width of the terminal in characters,
+// without splitting a multi-byte character.
+func TestTerminalStatusWidth(t *testing.T) {
+	frame := Frame{Turn: 1, Error: strings.Repeat("é", 20)}
+	// Leave room for five characters of the error after the status.
+	cols := utf8.RuneCountInString(strings.Join(StatusLines(frame, 0), "  ")+"  ") + 5
+
+	var out bytes.Buffer
+	r := newTerminalRenderer(6, 8, cols, 24, &out, make(chan byte))
+	r.Render(frame)
+
+	drawn := out.String()
+	status := drawn[strings.LastIndex(drawn, "\x1b[2K")+len("\x1b[2K"):]
+	if !utf8.ValidString(status) || utf8.RuneCountInString(status) != cols || !strings.HasSuffix(status, "ééééé") {
+		t.Errorf("expected a %v character status line ending in five of the error's characters, got %q", cols, status)
+	}
+}